@@ -1,10 +1,23 @@
 package main
 
 import (
+	"anikama-backend/internal/handlers"
+	"anikama-backend/internal/jobs"
+	"anikama-backend/internal/middleware"
 	"anikama-backend/internal/router"
+	"anikama-backend/internal/service"
 	"anikama-backend/pkg/db"
+	"anikama-backend/pkg/embedding"
+	"anikama-backend/pkg/eth"
+	"anikama-backend/pkg/gemini"
+	"anikama-backend/pkg/gotrue"
+	"anikama-backend/pkg/llm"
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,12 +29,75 @@ func main() {
 	}
 
 	// Initialize database connection
-if err := db.InitDB(); err != nil {
+	if err := db.InitDB(); err != nil {
 		log.Fatalf("❌ Failed to initialize database: %v", err)
 	}
 	defer db.CloseDB()
 
-	
+	// Initialize Supabase Auth client for server-side register/login
+	if authClient, err := gotrue.NewClientFromEnv(); err != nil {
+		log.Printf("⚠️  Auth endpoints disabled: %v", err)
+	} else {
+		handlers.SetAuthClient(authClient)
+	}
+
+	// Load personality rules from PERSONALITY_RULES_PATH (the built-in rules otherwise)
+	if path := os.Getenv("PERSONALITY_RULES_PATH"); path != "" {
+		rules, err := service.LoadRulesFile(path)
+		if err != nil {
+			log.Fatalf("❌ Failed to load personality rules: %v", err)
+		}
+		service.SetRules(rules)
+		go jobs.WatchRules(context.Background(), path, jobs.DefaultRulesReloadInterval)
+	}
+
+	// Initialize on-chain deposit verification and the worker confirming pending deposits
+	if verifier, err := eth.NewVerifierFromEnv(); err != nil {
+		log.Printf("⚠️  Deposits disabled: %v", err)
+	} else {
+		deposits := service.NewDepositService(db.DB, verifier)
+		handlers.SetDepositService(deposits)
+		go jobs.RunDepositWorker(context.Background(), deposits, jobs.DefaultDepositInterval)
+	}
+
+	// Downgrade users whose premium subscription has lapsed
+	go jobs.RunSubscriptionExpiry(context.Background(), db.DB, jobs.DefaultSubscriptionInterval)
+
+	// Keep cached Hush Coin balances in line with the ledger, alerting on drift
+	go jobs.RunLedgerReconciliation(context.Background(), db.DB, jobs.DefaultReconcileInterval)
+
+	// Cool off neglected relationships and keep their moods current
+	go jobs.RunRelationshipDecay(context.Background(), db.DB, service.DefaultDecayConfig(), jobs.DefaultDecayInterval)
+
+	// Initialize idempotent replay of retried requests
+	if store, err := newIdempotencyStore(); err != nil {
+		log.Printf("⚠️  Idempotency keys disabled: %v", err)
+	} else {
+		middleware.SetIdempotencyStore(store, idempotencyTTL())
+		go jobs.RunIdempotencyCleanup(context.Background(), store, jobs.DefaultIdempotencyCleanupInterval)
+	}
+
+	// Initialize the LLM provider shared by all chat requests, with timeouts,
+	// retries and circuit breaking in front of it
+	provider, err := newLLMProvider(context.Background())
+	if err != nil {
+		log.Printf("⚠️  Chat disabled: %v", err)
+	} else {
+		chatProvider := llm.NewResilient(provider, llm.DefaultResilienceConfig())
+		handlers.SetLLMProvider(chatProvider)
+		handlers.SetMemoryService(service.NewMemoryService(db.DB, provider))
+	}
+
+	// Initialize scoring of chat messages for the affinity engine (with its own
+	// circuit breaker, so it can't take chat down)
+	handlers.SetSentimentClassifier(newSentimentClassifier(provider))
+
+	// Initialize semantic recall over past exchanges
+	if recall, err := newRecallService(context.Background()); err != nil {
+		log.Printf("⚠️  Recall disabled: %v", err)
+	} else {
+		handlers.SetRecallService(recall)
+	}
 
 	// Setup router
 	r := router.SetupRouter()
@@ -34,9 +110,123 @@ if err := db.InitDB(); err != nil {
 
 	log.Printf("🚀 Anikama Backend starting on port %s", port)
 	log.Printf("📚 API Base URL: http://localhost:%s/api/v1", port)
-	
+
 	// Start server
 	if err := r.Run(":" + port); err != nil {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
 }
+
+var (
+	geminiOnce   sync.Once
+	geminiClient *gemini.Client
+	geminiErr    error
+)
+
+// sharedGeminiClient returns the process-wide Gemini client used for both
+// chat and embeddings, creating it on first use
+func sharedGeminiClient(ctx context.Context) (*gemini.Client, error) {
+	geminiOnce.Do(func() {
+		geminiClient, geminiErr = gemini.NewClient(ctx)
+	})
+	return geminiClient, geminiErr
+}
+
+// newLLMProvider picks the chat backend from LLM_PROVIDER: "gemini" (default),
+// "openai" for OpenAI-compatible local servers, or "fake" for offline development
+func newLLMProvider(ctx context.Context) (llm.Provider, error) {
+	// Return nil, not a typed nil client, on failure so provider != nil checks hold
+	switch provider := os.Getenv("LLM_PROVIDER"); provider {
+	case "", "gemini":
+		client, err := sharedGeminiClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "openai":
+		client, err := llm.NewOpenAIClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "fake":
+		log.Println("⚠️  Using the scripted fake LLM provider")
+		return llm.NewFake(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q", provider)
+	}
+}
+
+// newRecallService builds recall from EMBEDDING_PROVIDER ("gemini" by default, or
+// "local" for the offline hashing embedder) and RECALL_INDEX ("pgvector" by default,
+// or "memory" for a process-local index). EMBEDDING_PROVIDER=none disables recall.
+func newRecallService(ctx context.Context) (*service.RecallService, error) {
+	var embedder embedding.Embedder
+	switch provider := os.Getenv("EMBEDDING_PROVIDER"); provider {
+	case "", "gemini":
+		client, err := sharedGeminiClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		embedder = client
+	case "local":
+		embedder = embedding.NewHashEmbedder(embedding.DefaultDimensions)
+	case "none":
+		return nil, fmt.Errorf("EMBEDDING_PROVIDER is none")
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDING_PROVIDER %q", provider)
+	}
+
+	var index service.VectorIndex
+	switch kind := os.Getenv("RECALL_INDEX"); kind {
+	case "", "pgvector":
+		index = service.NewPgVectorIndex(db.DB)
+	case "memory":
+		index = service.NewInMemoryIndex()
+	default:
+		return nil, fmt.Errorf("unknown RECALL_INDEX %q", kind)
+	}
+
+	return service.NewRecallService(embedder, index), nil
+}
+
+// newIdempotencyStore picks where Idempotency-Key responses are kept from
+// IDEMPOTENCY_STORE: "postgres" (default), "memory" for a process-local store,
+// or "none" to ignore the header
+func newIdempotencyStore() (service.IdempotencyStore, error) {
+	switch kind := os.Getenv("IDEMPOTENCY_STORE"); kind {
+	case "", "postgres":
+		return service.NewPgIdempotencyStore(db.DB), nil
+	case "memory":
+		return service.NewInMemoryIdempotencyStore(), nil
+	case "none":
+		return nil, fmt.Errorf("IDEMPOTENCY_STORE is none")
+	default:
+		return nil, fmt.Errorf("unknown IDEMPOTENCY_STORE %q", kind)
+	}
+}
+
+// idempotencyTTL returns how long responses are kept for replay, from IDEMPOTENCY_TTL
+func idempotencyTTL() time.Duration {
+	if v, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && v > 0 {
+		return v
+	}
+	return middleware.DefaultIdempotencyTTL
+}
+
+// newSentimentClassifier picks how chat messages are scored from
+// SENTIMENT_CLASSIFIER: "llm" (default, needs a chat provider) or "rules" for
+// the offline keyword classifier
+func newSentimentClassifier(provider llm.Provider) service.SentimentClassifier {
+	switch kind := os.Getenv("SENTIMENT_CLASSIFIER"); kind {
+	case "", "llm":
+		if provider != nil {
+			return service.NewLLMClassifier(provider)
+		}
+		log.Println("⚠️  No chat provider, scoring chat messages with keyword rules")
+	case "rules":
+	default:
+		log.Printf("⚠️  Unknown SENTIMENT_CLASSIFIER %q, using keyword rules", kind)
+	}
+	return service.RuleClassifier{}
+}
@@ -6,12 +6,13 @@ import (
 
 // User represents a registered user profile
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Tier      string    `json:"tier"` // "free" or "premium"
-	HushCoins int       `json:"hush_coins"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Tier          string    `json:"tier"` // "free" or "premium"
+	HushCoins     int       `json:"hush_coins"`
+	WalletAddress string    `json:"wallet_address,omitempty"` // Linked via /wallet/link
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // Companion represents an anime character companion
@@ -31,12 +32,14 @@ type Companion struct {
 	Relationship      *Relationship `json:"relationship,omitempty"` // null if no relationship
 }
 
-// Relationship represents the dynamic affinity engine state
+// Relationship represents the dynamic affinity engine state and Nakama progression
 type Relationship struct {
 	UserID            string    `json:"user_id"`
 	CompanionID       string    `json:"companion_id"`
-	AffinityScore     int       `json:"affinity_score"`
+	AffinityScore     int       `json:"affinity_score"` // Bounded -100 to 100, drives the mood
 	CurrentMood       string    `json:"current_mood"`
+	XP                int       `json:"xp"`    // Nakama XP, never decays
+	Level             int       `json:"level"` // floor(xp / 100) + 1
 	LastInteractionAt time.Time `json:"last_interaction_at"`
 }
 
@@ -50,24 +53,16 @@ type Story struct {
 	OrderIndex  int       `json:"order_index"`
 	Mood        string    `json:"mood"`
 	IsPremium   bool      `json:"is_premium"`
-	IsLocked    bool      `json:"is_locked,omitempty"` // Computed field, not in DB
+	IsLocked    bool      `json:"is_locked,omitempty"`  // Computed field, not in DB
+	CoinPrice   int       `json:"coin_price,omitempty"` // Hush Coins to unlock, set while locked
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-// UserAffinity represents the user-companion relationship (Legacy/XP)
-type UserAffinity struct {
-	UserID          string    `json:"user_id"`
-	CompanionID     string    `json:"companion_id"`
-	XP              int       `json:"xp"`
-	Level           int       `json:"level"`
-	LastInteraction time.Time `json:"last_interaction"`
-	CreatedAt       time.Time `json:"created_at"`
-}
-
-// CompanionWithAffinity combines companion data with user affinity (Legacy)
-type CompanionWithAffinity struct {
-	Companion
-	UserAffinity *UserAffinity `json:"affinity,omitempty"`
+// StoryUnlockResponse is returned after buying a premium story
+type StoryUnlockResponse struct {
+	StoryID    string `json:"story_id"`
+	CoinsSpent int    `json:"coins_spent"`
+	Balance    int    `json:"balance"`
 }
 
 // StoriesGrouped represents stories grouped by companion
@@ -85,23 +80,22 @@ type ChatRequest struct {
 }
 
 type ChatResponse struct {
-	CompanionID string `json:"companion_id"`
-	Message     string `json:"message"`
-	IsLimited   bool   `json:"is_limited"` // True if response was limited due to free tier
-}
+	CompanionID   string `json:"companion_id"`
+	Message       string `json:"message"`
+	IsLimited     bool   `json:"is_limited"`            // True once the daily free allowance is used up
+	CoinsCharged  int    `json:"coins_charged"`         // Hush Coins this message cost
+	Balance       int    `json:"balance"`               // Hush Coins left
+	RemainingFree int    `json:"remaining_free"`        // Free messages left today, -1 if unlimited
+	IsFallback    bool   `json:"is_fallback,omitempty"` // True if the AI was unavailable and the reply is a placeholder
 
-// Interaction request type
-type InteractionRequest struct {
-	CompanionID string `json:"companion_id" binding:"required"`
-	Action      string `json:"action" binding:"required"` // "view_story" or "sent_msg"
-}
-
-type InteractionResponse struct {
-	CompanionID string `json:"companion_id"`
-	XP          int    `json:"xp"`
-	Level       int    `json:"level"`
-	XPGained    int    `json:"xp_gained"`
-	LeveledUp   bool   `json:"leveled_up"`
+	// How the message affected the relationship; unset if it couldn't be applied
+	Sentiment     string `json:"sentiment,omitempty"`      // kind, rude, flirty, dismissive or neutral
+	AffinityDelta int    `json:"affinity_delta,omitempty"` // Change in affinity score
+	AffinityScore int    `json:"affinity_score,omitempty"` // Affinity score after the message
+	Mood          string `json:"mood,omitempty"`           // Relationship mood after the message
+	XP            int    `json:"xp,omitempty"`             // Nakama XP after the message
+	Level         int    `json:"level,omitempty"`          // Nakama level after the message
+	LeveledUp     bool   `json:"leveled_up,omitempty"`     // True if the message reached a new level
 }
 
 // Auth types
@@ -110,9 +104,20 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"` // Username, or the email for accounts created on the frontend
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type AuthResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token,omitempty"`
+	User         User   `json:"user"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"` // Unix seconds
 }
 
 // Chat types
@@ -125,6 +130,15 @@ type Message struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// CompanionMemory is what a companion remembers about a user beyond the recent chat window
+type CompanionMemory struct {
+	CompanionID     string     `json:"companion_id"`
+	Summary         string     `json:"summary"`
+	Facts           []string   `json:"facts"`
+	SummarizedUntil *time.Time `json:"summarized_until,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
 type ChatHistoryResponse struct {
 	Messages []Message `json:"messages"`
 }
@@ -150,3 +164,115 @@ type Reaction struct {
 	SadReactionURL   string    `json:"sad_reaction_url"`
 	CreatedAt        time.Time `json:"created_at"`
 }
+
+// LedgerEntry is one change to the user's Hush Coin balance
+type LedgerEntry struct {
+	Seq           int64     `json:"id"`
+	TransactionID string    `json:"transaction_id"`
+	Kind          string    `json:"kind"` // "deposit", "chat_message", "reversal", ...
+	Reference     string    `json:"reference,omitempty"`
+	Amount        int       `json:"amount"` // Positive for credits, negative for debits
+	BalanceAfter  int       `json:"balance_after"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LedgerResponse is a page of the user's ledger, newest first
+type LedgerResponse struct {
+	Entries    []LedgerEntry `json:"entries"`
+	Balance    int           `json:"balance"`
+	NextBefore int64         `json:"next_before,omitempty"` // Pass as ?before= for the next page
+}
+
+// Deposit is the status of an ETH deposit for Hush Coins
+type Deposit struct {
+	TxHash                string     `json:"tx_hash"`
+	Status                string     `json:"status"` // "pending", "confirmed", "failed" or "needs_review"
+	AmountETH             string     `json:"amount_eth"`
+	Coins                 int        `json:"coins"` // Credited once confirmed
+	Confirmations         int        `json:"confirmations"`
+	RequiredConfirmations int        `json:"required_confirmations"`
+	FailureReason         string     `json:"failure_reason,omitempty"` // Why it failed or needs review
+	CreatedAt             time.Time  `json:"created_at"`
+	ConfirmedAt           *time.Time `json:"confirmed_at,omitempty"`
+}
+
+// CoinRate is an ETH to Hush Coin exchange rate
+type CoinRate struct {
+	ID            string    `json:"id"`
+	CoinsPerETH   int64     `json:"coins_per_eth"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CoinPack is a purchasable bundle of Hush Coins, priced at the current rate
+type CoinPack struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Coins      int    `json:"coins"`
+	BonusCoins int    `json:"bonus_coins"`
+	TotalCoins int    `json:"total_coins"`
+	PriceWei   string `json:"price_wei"` // Exact price; send this much
+	PriceETH   string `json:"price_eth"`
+}
+
+// PricingResponse is the current rate and pack prices
+type PricingResponse struct {
+	Rate  *CoinRate  `json:"rate"`
+	Packs []CoinPack `json:"packs"`
+}
+
+// PublishRateRequest schedules a new exchange rate (admin only)
+type PublishRateRequest struct {
+	CoinsPerETH   int64      `json:"coins_per_eth" binding:"required,gt=0"`
+	EffectiveFrom *time.Time `json:"effective_from"` // Defaults to now
+}
+
+// SubscriptionPlan is a premium plan bought with Hush Coins
+type SubscriptionPlan struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Days      int    `json:"days"`
+	CoinPrice int    `json:"coin_price"`
+}
+
+// Subscription is a user's premium subscription
+type Subscription struct {
+	PlanID     string    `json:"plan_id"`
+	Status     string    `json:"status"` // "active", "grace" (expired but still premium) or "expired"
+	StartedAt  time.Time `json:"started_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	GraceUntil time.Time `json:"grace_until"` // Premium is kept until then so the user can renew
+}
+
+// SubscriptionResponse is the caller's tier, subscription and the plans on sale
+type SubscriptionResponse struct {
+	Tier         string             `json:"tier"`
+	Subscription *Subscription      `json:"subscription"`
+	Plans        []SubscriptionPlan `json:"plans"`
+}
+
+// SubscribeRequest buys or renews premium with a plan
+type SubscribeRequest struct {
+	PlanID string `json:"plan_id" binding:"required"`
+}
+
+// SubscribeResponse is returned after buying or renewing premium
+type SubscribeResponse struct {
+	Tier         string        `json:"tier"`
+	Subscription *Subscription `json:"subscription"`
+	CoinsSpent   int           `json:"coins_spent"`
+	Balance      int           `json:"balance"`
+}
+
+// Gift is an item users can buy for a companion
+type Gift struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Emoji     string `json:"emoji"`
+	CoinPrice int    `json:"coin_price"`
+}
+
+// GiftRequest sends a gift to a companion
+type GiftRequest struct {
+	GiftID string `json:"gift_id" binding:"required"`
+}
@@ -2,13 +2,35 @@ package handlers
 
 import (
 	"anikama-backend/internal/domain"
+	"anikama-backend/pkg/db"
+	"anikama-backend/pkg/gotrue"
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
+	"regexp"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// authClient is the GoTrue client used by the auth handlers, set at startup
+var authClient *gotrue.Client
+
+// SetAuthClient configures the Supabase Auth client used by Register, Login and Refresh
+func SetAuthClient(client *gotrue.Client) {
+	authClient = client
+}
+
+// usernameToEmail converts a username to the dummy email used for Supabase Auth
+// Format: {username}@anikama.app
+func usernameToEmail(username string) string {
+	return fmt.Sprintf("%s@anikama.app", strings.ToLower(username))
+}
+
 // Register handles user registration with username-to-email conversion
 func Register(c *gin.Context) {
 	var req domain.RegisterRequest
@@ -17,42 +39,165 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Convert username to dummy email for Supabase Auth
-	// Format: {username}@anikama.app
-	email := fmt.Sprintf("%s@anikama.app", req.Username)
-
-	// TODO: Call Supabase Auth API to create user
-	// For now, this is a placeholder that explains the flow
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseKey := os.Getenv("SUPABASE_ANON_KEY")
+	if !usernamePattern.MatchString(req.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username may only contain letters, numbers and underscores"})
+		return
+	}
 
-	if supabaseURL == "" || supabaseKey == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Supabase configuration missing",
-			"note":  "This endpoint requires Supabase URL and Anon Key to be configured",
-		})
+	if authClient == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Supabase configuration missing"})
 		return
 	}
 
-	// The actual implementation would:
-	// 1. POST to {SUPABASE_URL}/auth/v1/signup
-	// 2. Send: { email, password, options: { data: { username } } }
-	// 3. The database trigger will create the profile automatically
-	// 4. Return the session token
+	// 1. Usernames are unique on profiles; catch clashes before GoTrue's trigger fails
+	var taken bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM profiles WHERE lower(username) = lower($1))`, req.Username).Scan(&taken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check username"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "Registration endpoint ready - implement Supabase auth integration",
-		"email_format": email,
-		"username":     req.Username,
-		"note":         "Frontend should handle Supabase auth directly using supabase-js client",
+	// 2. Create the auth user; the on_auth_user_created trigger creates the profile
+	user, session, err := authClient.SignUp(c.Request.Context(), usernameToEmail(req.Username), req.Password, map[string]interface{}{
+		"username": req.Username,
 	})
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	resp := domain.AuthResponse{
+		User: domain.User{
+			ID:        user.ID,
+			Username:  req.Username,
+			Tier:      "free",
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.CreatedAt,
+		},
+	}
+	if session != nil {
+		resp.Token = session.AccessToken
+		resp.RefreshToken = session.RefreshToken
+		resp.ExpiresAt = session.ExpiresAt
+	}
+
+	c.JSON(http.StatusCreated, resp)
 }
 
-// Login handles user login
-// Note: In practice, login should be handled by Supabase Auth on the frontend
+// Login exchanges username and password for a Supabase session
 func Login(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login should be handled by Supabase Auth on frontend",
-		"note":    "Use supabase.auth.signInWithPassword() with converted email",
-	})
+	var req domain.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if authClient == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Supabase configuration missing"})
+		return
+	}
+
+	email := req.Username
+	if !strings.Contains(email, "@") {
+		email = usernameToEmail(req.Username)
+	}
+
+	session, err := authClient.SignInWithPassword(c.Request.Context(), email, req.Password)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionResponse(c.Request.Context(), session))
+}
+
+// Refresh exchanges a refresh token for a new Supabase session
+func Refresh(c *gin.Context) {
+	var req domain.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if authClient == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Supabase configuration missing"})
+		return
+	}
+
+	session, err := authClient.RefreshSession(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionResponse(c.Request.Context(), session))
+}
+
+// sessionResponse builds the AuthResponse, preferring the stored profile for user details
+func sessionResponse(ctx context.Context, session *gotrue.Session) domain.AuthResponse {
+	resp := domain.AuthResponse{
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		ExpiresAt:    session.ExpiresAt,
+	}
+
+	err := db.DB.QueryRowContext(ctx, `
+		SELECT id, username, tier, COALESCE(hush_coins, 0), COALESCE(wallet_address, ''), created_at, updated_at
+		FROM profiles
+		WHERE id = $1
+	`, session.User.ID).Scan(
+		&resp.User.ID,
+		&resp.User.Username,
+		&resp.User.Tier,
+		&resp.User.HushCoins,
+		&resp.User.WalletAddress,
+		&resp.User.CreatedAt,
+		&resp.User.UpdatedAt,
+	)
+	if err != nil {
+		// Fall back to what GoTrue told us about the user
+		username, _ := session.User.UserMetadata["username"].(string)
+		resp.User = domain.User{
+			ID:        session.User.ID,
+			Username:  username,
+			Tier:      "free",
+			CreatedAt: session.User.CreatedAt,
+			UpdatedAt: session.User.CreatedAt,
+		}
+	}
+
+	return resp
+}
+
+// respondAuthError maps GoTrue failures to client-facing status codes
+func respondAuthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gotrue.ErrUserExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+	case errors.Is(err, gotrue.ErrWeakPassword):
+		var gtErr *gotrue.Error
+		msg := "Password is too weak"
+		if errors.As(err, &gtErr) && gtErr.Message != "" {
+			msg = gtErr.Message
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+	case errors.Is(err, gotrue.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	case errors.Is(err, gotrue.ErrInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+	case errors.Is(err, gotrue.ErrRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, please try again later"})
+	default:
+		var gtErr *gotrue.Error
+		if errors.As(err, &gtErr) && gtErr.StatusCode >= 400 && gtErr.StatusCode < 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gtErr.Message})
+			return
+		}
+		log.Printf("auth server error: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Authentication service unavailable"})
+	}
 }
@@ -2,151 +2,390 @@ package handlers
 
 import (
 	"anikama-backend/internal/domain"
+	"anikama-backend/internal/middleware"
+	"anikama-backend/internal/service"
 	"anikama-backend/pkg/db"
-	"anikama-backend/pkg/gemini"
+	"anikama-backend/pkg/llm"
 	"context"
 	"database/sql"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Chat handles AI chat requests with companions
-func Chat(c *gin.Context) {
+const (
+	// Free tier gets max 256 tokens (shorter responses)
+	freeMaxTokens int32 = 256
+
+	// Premium tier gets max 1024 tokens (longer responses)
+	premiumMaxTokens int32 = 1024
+
+	// Upper bound on history rows loaded before the token budget is applied
+	maxHistoryMessages = 100
+
+	// Default input budget (system prompt + history + message), see CHAT_CONTEXT_TOKEN_BUDGET
+	defaultContextTokenBudget = 4000
+)
+
+// contextTokenBudget returns the estimated input token budget for one chat request
+func contextTokenBudget() int {
+	if v, err := strconv.Atoi(os.Getenv("CHAT_CONTEXT_TOKEN_BUDGET")); err == nil && v > 0 {
+		return v
+	}
+	return defaultContextTokenBudget
+}
+
+// llmProvider generates companion replies, set at startup
+var llmProvider llm.Provider
+
+// SetLLMProvider configures the LLM provider used by Chat and ChatStream
+func SetLLMProvider(provider llm.Provider) {
+	llmProvider = provider
+}
+
+// chatTurn holds everything needed to generate a companion's reply to one user message
+type chatTurn struct {
+	UserID        string
+	CompanionID   string
+	CompanionName string
+	SystemPrompt  string
+	History       []llm.Message // Earlier turns that fit the token budget, oldest first
+	Prompt        string        // The new user message
+	Personality   service.PersonalityType
+	Tier          string
+	Options       llm.GenerationOptions
+	Charge        *service.ChatCharge
+}
+
+// companionGenerationConfig is the companions.generation_config column
+type companionGenerationConfig struct {
+	Default llm.GenerationOptions            `json:"default"`
+	Tiers   map[string]llm.GenerationOptions `json:"tiers"`
+}
+
+// generationOptions resolves the options for one request: app defaults, then the
+// companion's defaults, then the companion's override for the user's tier
+func generationOptions(tier string, raw []byte) llm.GenerationOptions {
+	opts := llm.GenerationOptions{MaxTokens: freeMaxTokens}
+	if tier == service.TierPremium {
+		opts.MaxTokens = premiumMaxTokens
+	}
+
+	var cfg companionGenerationConfig
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			log.Printf("invalid companion generation_config, using defaults: %v", err)
+			return opts
+		}
+	}
+
+	return opts.Merge(cfg.Default).Merge(cfg.Tiers[tier])
+}
+
+// prepareChatTurn validates the request, loads recent history and saves the user
+// message. On failure it writes the error response and returns false.
+func prepareChatTurn(c *gin.Context) (*chatTurn, bool) {
 	userIdStr, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
-		return
+		return nil, false
 	}
 	userID := userIdStr.(string)
 
 	var req domain.ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 1. Get user tier
-	var tier string
-	tierQuery := `SELECT tier FROM profiles WHERE id = $1`
-	err := db.DB.QueryRow(tierQuery, userID).Scan(&tier)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user tier"})
-		return
+		return nil, false
 	}
 
-	// 2. Get companion's system prompt & name
+	// 1. Get companion's system prompt, name, personality, prompt template & generation config
 	var systemPrompt, companionName string
-	promptQuery := `SELECT system_prompt, name FROM companions WHERE id = $1`
-	err = db.DB.QueryRow(promptQuery, req.CompanionID).Scan(&systemPrompt, &companionName)
+	var personalityType, promptTemplate sql.NullString
+	var generationConfig []byte
+	promptQuery := `SELECT system_prompt, name, personality_type, prompt_template, generation_config FROM companions WHERE id = $1`
+	err := db.DB.QueryRow(promptQuery, req.CompanionID).Scan(&systemPrompt, &companionName, &personalityType, &promptTemplate, &generationConfig)
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Companion not found"})
-		return
+		return nil, false
 	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch companion"})
-		return
+		return nil, false
 	}
 
-	// 3. Save User Message to DB
-	_, err = db.DB.Exec(`
-		INSERT INTO messages (user_id, companion_id, role, content) 
-		VALUES ($1, $2, 'user', $3)
-	`, userID, req.CompanionID, req.Message)
+	personality := service.DefaultPersonality
+	if personalityType.Valid {
+		personality = service.PersonalityType(personalityType.String)
+	}
+
+	// 1b. Tell the companion how it feels about the user, so replies match the relationship's mood
+	mood, err := service.LoadMoodPromptData(c.Request.Context(), db.DB, userID, req.CompanionID, companionName, personality)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user message"})
-		return
+		log.Printf("failed to load relationship for user %s: %v", userID, err)
+	} else if section, err := service.FormatMoodPrompt(req.CompanionID, promptTemplate.String, mood); err != nil {
+		log.Printf("companion %s: %v", req.CompanionID, err)
+	} else {
+		systemPrompt += "\n\n" + section
 	}
 
-	// 4. Retrieve Chat History (Last 10 messages)
+	// 2. Add what the companion remembers from older conversations
+	if memoryService != nil {
+		mem, err := memoryService.Get(c.Request.Context(), userID, req.CompanionID)
+		if err != nil {
+			log.Printf("failed to load memory for user %s: %v", userID, err)
+		} else if section := service.FormatMemory(mem); section != "" {
+			systemPrompt += "\n\n" + section
+		}
+	}
+
+	// 3. Retrieve recent chat history (newest first, trimmed to the token budget below)
 	rows, err := db.DB.Query(`
-		SELECT role, content FROM (
-			SELECT role, content, created_at 
-			FROM messages 
-			WHERE user_id = $1 AND companion_id = $2
-			ORDER BY created_at DESC 
-			LIMIT 10
-		) sub ORDER BY created_at ASC
-	`, userID, req.CompanionID)
+		SELECT role, content, created_at
+		FROM messages 
+		WHERE user_id = $1 AND companion_id = $2
+		ORDER BY created_at DESC 
+		LIMIT $3
+	`, userID, req.CompanionID, maxHistoryMessages)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chat history"})
-		return
+		return nil, false
 	}
 	defer rows.Close()
 
-	var historyContext string
+	var history []llm.Message
+	var sentAt []time.Time
 	for rows.Next() {
 		var role, content string
-		if err := rows.Scan(&role, &content); err == nil {
-			// Format: "User: hello" or "CharacterName: hi"
-			speaker := "User"
+		var createdAt time.Time
+		if err := rows.Scan(&role, &content, &createdAt); err == nil {
+			msgRole := llm.RoleUser
 			if role == "assistant" {
-				speaker = companionName
+				msgRole = llm.RoleModel
 			}
-			historyContext += fmt.Sprintf("%s: %s\n", speaker, content)
+			history = append(history, llm.Message{Role: msgRole, Content: content})
+			sentAt = append(sentAt, createdAt)
 		}
 	}
+	rows.Close()
 
-	// 5. Initialize Gemini client
-	ctx := context.Background()
-	geminiClient, err := gemini.NewClient(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize AI client"})
-		return
+	// Reverse into chronological order
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+		sentAt[i], sentAt[j] = sentAt[j], sentAt[i]
 	}
 
-	// 6. Generate Context-Aware Prompt
-	// We prepend the history to the current message for the AI
-	// Note: Ideally we'd use Gemini's chat history API, but for simplicity/statelessness
-	// we'll append recent history to the prompt.
-	fullPrompt := fmt.Sprintf("History:\n%s\nUser: %s\n", historyContext, req.Message)
+	// 4. Save the message and charge it against the tier's daily allowance / Hush Coins, atomically
+	ctx := c.Request.Context()
+	tier, err := service.ResolveTier(ctx, db.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tier"})
+		return nil, false
+	}
 
-	// 7. Generate response based on tier
-	var response string
-	var isLimited bool
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return nil, false
+	}
+	defer tx.Rollback()
 
-	// Using the system prompt as the base instruction
-	if tier == "premium" {
-		response, err = geminiClient.GenerateResponsePremium(ctx, systemPrompt, fullPrompt)
-		isLimited = false
-	} else {
-		// allow free chat for now as requested
-		response, err = geminiClient.GenerateResponsePremium(ctx, systemPrompt, fullPrompt)
-		isLimited = false
+	var messageID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO messages (user_id, companion_id, role, content) 
+		VALUES ($1, $2, 'user', $3)
+		RETURNING id
+	`, userID, req.CompanionID, req.Message).Scan(&messageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user message"})
+		return nil, false
 	}
 
+	charge, err := service.ChargeChatMessage(ctx, tx, userID, tier, messageID)
+	if errors.Is(err, service.ErrInsufficientCoins) {
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":       "Daily message limit reached and not enough Hush Coins",
+			"daily_limit": charge.DailyLimit,
+			"cost":        charge.CoinsCharged,
+			"balance":     charge.Balance,
+		})
+		return nil, false
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate response"})
-		return
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to charge message"})
+		return nil, false
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return nil, false
 	}
 
-	// 8. Save Assistant Response to DB
-	_, err = db.DB.Exec(`
+	// 5. Keep as much recent history as fits alongside the system prompt and new message
+	kept := llm.TrimHistory(history, historyBudget(systemPrompt, req.Message))
+
+	// 5b. Recall relevant exchanges from before the chat window, then re-fit the history
+	if recallService != nil {
+		before := time.Now()
+		if len(kept) > 0 {
+			before = sentAt[len(history)-len(kept)]
+		}
+		hits, err := recallService.Recall(ctx, userID, req.CompanionID, req.Message, before)
+		if err != nil {
+			log.Printf("failed to recall past exchanges for user %s: %v", userID, err)
+		} else if section := service.FormatRecall(hits); section != "" {
+			systemPrompt += "\n\n" + section
+			kept = llm.TrimHistory(kept, historyBudget(systemPrompt, req.Message))
+		}
+	}
+
+	return &chatTurn{
+		UserID:        userID,
+		CompanionID:   req.CompanionID,
+		CompanionName: companionName,
+		SystemPrompt:  systemPrompt,
+		History:       kept,
+		Prompt:        req.Message,
+		Personality:   personality,
+		Tier:          tier,
+		Options:       generationOptions(tier, generationConfig),
+		Charge:        charge,
+	}, true
+}
+
+// historyBudget returns the tokens left for history after the system prompt and new message
+func historyBudget(systemPrompt, message string) int {
+	budget := contextTokenBudget() - llm.EstimateTokens(systemPrompt) - llm.EstimateTokens(message)
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// saveAssistantReply stores the assistant message and bumps the chat in the chats list
+func saveAssistantReply(ctx context.Context, turn *chatTurn, response string) error {
+	var messageID string
+	var createdAt time.Time
+	err := db.DB.QueryRowContext(ctx, `
 		INSERT INTO messages (user_id, companion_id, role, content) 
 		VALUES ($1, $2, 'assistant', $3)
-	`, userID, req.CompanionID, response)
+		RETURNING id, created_at
+	`, turn.UserID, turn.CompanionID, response).Scan(&messageID, &createdAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save assistant message"})
-		return
+		return err
 	}
 
-	// 9. Update Chats Table (Upsert)
-	_, err = db.DB.Exec(`
+	// Update Chats Table (Upsert)
+	_, err = db.DB.ExecContext(ctx, `
 		INSERT INTO chats (user_id, companion_id, last_message, last_message_at)
 		VALUES ($1, $2, $3, NOW())
 		ON CONFLICT (user_id, companion_id) 
 		DO UPDATE SET last_message = EXCLUDED.last_message, last_message_at = NOW();
-	`, userID, req.CompanionID, response)
+	`, turn.UserID, turn.CompanionID, response)
 	if err != nil {
 		// Log error but don't fail the request as the message was sent
+		log.Printf("failed to update chats for user %s: %v", turn.UserID, err)
+	}
+
+	// Fold messages that have left the chat window into long-term memory
+	if memoryService != nil {
+		memoryService.SummarizeAsync(turn.UserID, turn.CompanionID, turn.CompanionName)
+	}
+
+	// Make the exchange searchable for semantic recall
+	if recallService != nil {
+		recallService.IndexAsync(service.RecallEntry{
+			ID:          messageID,
+			UserID:      turn.UserID,
+			CompanionID: turn.CompanionID,
+			Text:        "User: " + turn.Prompt + "\n" + turn.CompanionName + ": " + response,
+			CreatedAt:   createdAt,
+		})
+	}
+
+	return nil
+}
+
+// Chat handles AI chat requests with companions
+func Chat(c *gin.Context) {
+	// Check the AI provider before the message is charged
+	if llmProvider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI provider not configured"})
+		return
 	}
 
-	c.JSON(http.StatusOK, domain.ChatResponse{
-		CompanionID: req.CompanionID,
-		Message:     response,
-		IsLimited:   isLimited,
+	turn, ok := prepareChatTurn(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	// 6. Generate response, scoring the user's message meanwhile
+	sentiments := classifyAsync(ctx, turn.Prompt)
+	response, err := llmProvider.Generate(ctx, llm.Request{
+		SystemPrompt: turn.SystemPrompt,
+		History:      turn.History,
+		Prompt:       turn.Prompt,
+		Options:      turn.Options,
 	})
+	if err != nil {
+		// Don't charge for (or keep) a message the user got no reply to
+		refundChatTurn(turn)
+	}
+	if errors.Is(err, llm.ErrUnavailable) {
+		log.Printf("LLM unavailable for user %s: %v", turn.UserID, err)
+		resp := chatResponse(turn, fallbackReply(turn.CompanionName))
+		resp.IsFallback = true
+		middleware.SkipIdempotentReplay(c) // Retrying should get a real reply
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate response"})
+		return
+	}
+
+	// 7. Save Assistant Response to DB and update the chats list
+	if err := saveAssistantReply(ctx, turn, response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save assistant message"})
+		return
+	}
+
+	// 8. Apply the message's sentiment to the relationship
+	resp := chatResponse(turn, response)
+	applyChatSentiment(ctx, turn, sentiments, &resp)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// chatResponse builds the reply payload including the user's quota state
+func chatResponse(turn *chatTurn, message string) domain.ChatResponse {
+	return domain.ChatResponse{
+		CompanionID:   turn.CompanionID,
+		Message:       message,
+		IsLimited:     turn.Charge.Limited(),
+		CoinsCharged:  turn.Charge.CoinsCharged,
+		Balance:       turn.Charge.Balance,
+		RemainingFree: turn.Charge.RemainingFree(),
+	}
+}
+
+// refundChatTurn undoes the turn's charge and drops its user message after
+// generation failed
+func refundChatTurn(turn *chatTurn) {
+	if err := service.RefundChatMessage(context.Background(), db.DB, turn.UserID, turn.Charge); err != nil {
+		log.Printf("failed to refund chat message for user %s: %v", turn.UserID, err)
+		return
+	}
+	turn.Charge.UsedToday--
+	turn.Charge.Balance += turn.Charge.CoinsCharged
+	turn.Charge.CoinsCharged = 0
+	turn.Charge.LedgerTxID = ""
 }
 
 // GetChats returns the list of active chats for the user
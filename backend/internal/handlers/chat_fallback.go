@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// fallbackReplies are sent in character when the AI provider is unavailable.
+// Neither they nor the user's message are saved (see RefundChatMessage), so
+// the user can simply send the message again.
+var fallbackReplies = []string{
+	"*%s blinks, lost in thought for a moment* ...Sorry, my head's a little foggy right now. Can you say that again in a bit?",
+	"*%s pauses and looks away* Hmm... give me a second, I can't quite find the words. Try me again soon?",
+	"*%s yawns softly* Ah, sorry! I drifted off for a moment there. Talk to me again in a little while?",
+}
+
+// fallbackReply returns an in-character placeholder reply for the companion
+func fallbackReply(companionName string) string {
+	return fmt.Sprintf(fallbackReplies[rand.Intn(len(fallbackReplies))], companionName)
+}
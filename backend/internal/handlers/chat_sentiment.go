@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
+	"anikama-backend/pkg/db"
+	"context"
+	"log"
+)
+
+// sentimentClassifier scores chat messages for the affinity engine, set at startup
+var sentimentClassifier service.SentimentClassifier
+
+// SetSentimentClassifier configures how Chat and ChatStream score user messages
+func SetSentimentClassifier(classifier service.SentimentClassifier) {
+	sentimentClassifier = classifier
+}
+
+// classifyAsync starts classifying the user's message so it runs alongside
+// reply generation. The result is dropped if no reply is saved.
+func classifyAsync(ctx context.Context, message string) <-chan service.Sentiment {
+	if sentimentClassifier == nil {
+		return nil
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	sentiments := make(chan service.Sentiment, 1)
+	go func() {
+		sentiment, err := sentimentClassifier.Classify(ctx, message)
+		if err != nil {
+			log.Printf("failed to classify chat message: %v", err)
+			sentiment = service.SentimentNeutral
+		}
+		sentiments <- sentiment
+	}()
+	return sentiments
+}
+
+// applyChatSentiment turns the message's sentiment into a delta for the
+// companion's personality, applies it like a story reaction along with the
+// message's XP and adds the result to resp. Without a classifier only XP is
+// awarded. Failures are logged; the reply is sent either way.
+func applyChatSentiment(ctx context.Context, turn *chatTurn, sentiments <-chan service.Sentiment, resp *domain.ChatResponse) {
+	ctx = context.WithoutCancel(ctx)
+	var sentiment service.Sentiment
+	delta := 0
+	if sentiments != nil {
+		sentiment = <-sentiments
+		delta = service.CalculateDelta(turn.Personality, sentiment.Reaction(), "")
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("failed to start transaction: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := service.ApplyAffinityDelta(ctx, tx, turn.UserID, turn.CompanionID, turn.Personality, delta, service.GetXPForAction(service.XPActionSentMsg))
+	if err != nil {
+		log.Printf("failed to apply chat sentiment for user %s: %v", turn.UserID, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("failed to apply chat sentiment for user %s: %v", turn.UserID, err)
+		return
+	}
+
+	resp.Sentiment = string(sentiment)
+	resp.AffinityDelta = result.Delta
+	resp.AffinityScore = result.NewScore
+	resp.Mood = string(result.NewMood)
+	resp.XP = result.XP
+	resp.Level = result.Level
+	resp.LeveledUp = result.LeveledUp
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"anikama-backend/internal/middleware"
+	"anikama-backend/pkg/llm"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChatStream handles AI chat requests like Chat but streams the reply as
+// Server-Sent Events:
+//
+//	event: delta  data: {"text": "..."}   one per generated chunk
+//	event: done   data: ChatResponse       once the reply has been saved and scored
+//	event: error  data: {"error": "..."}   if generation fails mid-stream
+//
+// If the AI provider is unavailable before anything was streamed, an
+// in-character fallback is sent as a single delta and a done event with
+// is_fallback set.
+//
+// The assistant message is only persisted once the stream completes. If the
+// client disconnects, the request context is cancelled and generation stops.
+func ChatStream(c *gin.Context) {
+	// Check the AI provider before the message is charged
+	if llmProvider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI provider not configured"})
+		return
+	}
+
+	turn, ok := prepareChatTurn(c)
+	if !ok {
+		return
+	}
+	streamChatTurn(c, turn)
+}
+
+// streamChatTurn streams the reply to a charged turn and saves it. The stream
+// has already answered 200 by the time anything fails, so failed turns are
+// kept out of the idempotency store to let a retry run them again.
+func streamChatTurn(c *gin.Context, turn *chatTurn) {
+	ctx := c.Request.Context()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	req := llm.Request{
+		SystemPrompt: turn.SystemPrompt,
+		History:      turn.History,
+		Prompt:       turn.Prompt,
+		Options:      turn.Options,
+	}
+	sentiments := classifyAsync(ctx, turn.Prompt)
+	response, err := llmProvider.Stream(ctx, req, func(delta string) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.SSEvent("delta", gin.H{"text": delta})
+		c.Writer.Flush()
+		return nil
+	})
+
+	if err != nil {
+		// No reply is saved, so the message is neither charged nor kept
+		refundChatTurn(turn)
+
+		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			// Client went away; nothing left to send or save
+			log.Printf("chat stream cancelled for user %s", turn.UserID)
+			middleware.SkipIdempotentReplay(c)
+			return
+		}
+		if errors.Is(err, llm.ErrUnavailable) && response == "" {
+			log.Printf("LLM unavailable for user %s: %v", turn.UserID, err)
+			fallback := fallbackReply(turn.CompanionName)
+			c.SSEvent("delta", gin.H{"text": fallback})
+			done := chatResponse(turn, fallback)
+			done.IsFallback = true
+			middleware.SkipIdempotentReplay(c) // Retrying should get a real reply
+			c.SSEvent("done", done)
+			c.Writer.Flush()
+			return
+		}
+		middleware.SkipIdempotentReplay(c)
+		c.SSEvent("error", gin.H{"error": "Failed to generate response"})
+		c.Writer.Flush()
+		return
+	}
+
+	// The reply is complete; keep it even if the client disconnects while we save
+	if err := saveAssistantReply(context.WithoutCancel(ctx), turn, response); err != nil {
+		log.Printf("failed to save assistant message for user %s: %v", turn.UserID, err)
+		middleware.SkipIdempotentReplay(c)
+		c.SSEvent("error", gin.H{"error": "Failed to save assistant message"})
+		c.Writer.Flush()
+		return
+	}
+
+	done := chatResponse(turn, response)
+	applyChatSentiment(ctx, turn, sentiments, &done)
+
+	c.SSEvent("done", done)
+	c.Writer.Flush()
+}
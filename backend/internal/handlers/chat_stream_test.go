@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"anikama-backend/internal/middleware"
+	"anikama-backend/internal/service"
+	"anikama-backend/pkg/db"
+	"anikama-backend/pkg/llm"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// downDriver is a database that can't be reached, so refunds and saves fail
+type downDriver struct{}
+
+func (downDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("database is down")
+}
+
+func init() {
+	sql.Register("down", downDriver{})
+}
+
+// failingStream fails every stream with err
+type failingStream struct {
+	*llm.Fake
+	err error
+}
+
+func (f failingStream) Stream(ctx context.Context, req llm.Request, onDelta func(string) error) (string, error) {
+	return "", f.err
+}
+
+// newStreamRouter serves POST /chat/stream with a charged turn behind the
+// idempotency middleware, streaming with provider against an unreachable
+// database
+func newStreamRouter(t *testing.T, provider llm.Provider) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	down, err := sql.Open("down", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	prevDB, prevProvider := db.DB, llmProvider
+	db.DB, llmProvider = down, provider
+	middleware.SetIdempotencyStore(service.NewInMemoryIdempotencyStore(), time.Hour)
+	t.Cleanup(func() {
+		db.DB, llmProvider = prevDB, prevProvider
+		middleware.SetIdempotencyStore(nil, middleware.DefaultIdempotencyTTL)
+		down.Close()
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", "user-1") }, middleware.Idempotency())
+	r.POST("/chat/stream", func(c *gin.Context) {
+		streamChatTurn(c, &chatTurn{
+			UserID:        "user-1",
+			CompanionID:   "companion-1",
+			CompanionName: "Aiko",
+			Prompt:        "hi",
+			Charge:        &service.ChatCharge{MessageID: "message-1", Day: time.Now()},
+		})
+	})
+	return r
+}
+
+func sendStream(r *gin.Engine) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/chat/stream", strings.NewReader(`{"message":"hi"}`))
+	req.Header.Set(middleware.IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestChatStreamFailuresAreNotReplayed(t *testing.T) {
+	tests := []struct {
+		name      string
+		streamErr error // nil streams a reply that then fails to save
+		wantEvent string
+	}{
+		{"cancelled", context.Canceled, ""},
+		{"generation failed", errors.New("boom"), "Failed to generate response"},
+		{"save failed", nil, "Failed to save assistant message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := llm.NewFake("hello there")
+			var provider llm.Provider = fake
+			if tt.streamErr != nil {
+				provider = failingStream{Fake: fake, err: tt.streamErr}
+			}
+			r := newStreamRouter(t, provider)
+
+			first := sendStream(r)
+			if !strings.Contains(first.Body.String(), tt.wantEvent) {
+				t.Errorf("body = %q, want %q", first.Body, tt.wantEvent)
+			}
+
+			retry := sendStream(r)
+			if retry.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+				t.Fatalf("retry replayed the failed stream: %q", retry.Body)
+			}
+			if tt.streamErr == nil && len(fake.Requests()) != 2 {
+				t.Errorf("provider ran %d times, want 2", len(fake.Requests()))
+			}
+		})
+	}
+}
@@ -10,34 +10,86 @@ import (
 	"github.com/lib/pq"
 )
 
-// GetCompanions returns all companions with their mood/status and user relationship if authenticated
-func GetCompanions(c *gin.Context) {
-	userID, _ := c.Get("user_id") // Optional auth
+// companionQuery selects companions with the user's relationship ($1, may be
+// NULL); scanCompanion reads its rows
+const companionQuery = `
+	SELECT c.id, c.name, c.anime_source, c.archetype, c.avatar_url, 
+	       c.personality_traits, c.tags, 
+	       COALESCE(c.system_prompt, ''), 
+	       COALESCE(c.mood, 'Neutral'), 
+	       COALESCE(c.personality_type, 'Deredere'), 
+	       c.created_at,
+	       EXISTS(SELECT 1 FROM stories s WHERE s.companion_id = c.id) as has_stories,
+	       r.user_id, r.companion_id, r.affinity_score, r.current_mood, r.xp, r.level, r.last_interaction_at
+	FROM companions c
+	LEFT JOIN relationships r ON c.id = r.companion_id AND r.user_id = $1
+`
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCompanion reads a companionQuery row, attaching the relationship if there is one
+func scanCompanion(row rowScanner) (domain.Companion, error) {
+	var comp domain.Companion
+
+	// Nullable fields for relationship
+	var rUserID, rCompanionID, rCurrentMood sql.NullString
+	var rAffinityScore, rXP, rLevel sql.NullInt64
+	var rLastInteractionAt sql.NullTime
+
+	err := row.Scan(
+		&comp.ID,
+		&comp.Name,
+		&comp.AnimeSource,
+		&comp.Archetype,
+		&comp.AvatarURL,
+		pq.Array(&comp.PersonalityTraits),
+		pq.Array(&comp.Tags),
+		&comp.SystemPrompt,
+		&comp.Mood,
+		&comp.PersonalityType,
+		&comp.CreatedAt,
+		&comp.HasStories,
+		&rUserID,
+		&rCompanionID,
+		&rAffinityScore,
+		&rCurrentMood,
+		&rXP,
+		&rLevel,
+		&rLastInteractionAt,
+	)
+	if err != nil {
+		return comp, err
+	}
 
-	// Base query
-	query := `
-		SELECT c.id, c.name, c.anime_source, c.archetype, c.avatar_url, 
-		       c.personality_traits, c.tags, 
-		       COALESCE(c.system_prompt, ''), 
-		       COALESCE(c.mood, 'Neutral'), 
-		       COALESCE(c.personality_type, 'Deredere'), 
-		       c.created_at,
-		       EXISTS(SELECT 1 FROM stories s WHERE s.companion_id = c.id) as has_stories,
-		       r.user_id, r.companion_id, r.affinity_score, r.current_mood, r.last_interaction_at
-		FROM companions c
-		LEFT JOIN relationships r ON c.id = r.companion_id AND r.user_id = $1
-		ORDER BY c.created_at ASC
-	`
+	// Map relationship if it exists
+	if rUserID.Valid {
+		comp.Relationship = &domain.Relationship{
+			UserID:            rUserID.String,
+			CompanionID:       rCompanionID.String,
+			AffinityScore:     int(rAffinityScore.Int64),
+			CurrentMood:       rCurrentMood.String,
+			XP:                int(rXP.Int64),
+			Level:             int(rLevel.Int64),
+			LastInteractionAt: rLastInteractionAt.Time,
+		}
+	}
+	return comp, nil
+}
 
-	// Prepare userID for query (handle nil/empty)
-	var queryUserID interface{}
-	if userID != nil {
-		queryUserID = userID
-	} else {
-		queryUserID = nil // SQL driver handles nil as NULL
+// optionalUserID returns the user ID for companionQuery, or nil (NULL) if not authenticated
+func optionalUserID(c *gin.Context) interface{} {
+	if userID, exists := c.Get("user_id"); exists {
+		return userID
 	}
+	return nil
+}
 
-	rows, err := db.DB.Query(query, queryUserID)
+// GetCompanions returns all companions with their mood/status and user relationship if authenticated
+func GetCompanions(c *gin.Context) {
+	rows, err := db.DB.Query(companionQuery+` ORDER BY c.created_at ASC`, optionalUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch companions"})
 		return
@@ -46,47 +98,10 @@ func GetCompanions(c *gin.Context) {
 
 	companions := []domain.Companion{}
 	for rows.Next() {
-		var comp domain.Companion
-		var rel domain.Relationship
-
-		// Nullable fields for relationship
-		var rUserID, rCompanionID, rCurrentMood sql.NullString
-		var rAffinityScore sql.NullInt64
-		var rLastInteractionAt sql.NullTime
-
-		err := rows.Scan(
-			&comp.ID,
-			&comp.Name,
-			&comp.AnimeSource,
-			&comp.Archetype,
-			&comp.AvatarURL,
-			pq.Array(&comp.PersonalityTraits),
-			pq.Array(&comp.Tags),
-			&comp.SystemPrompt,
-			&comp.Mood,
-			&comp.PersonalityType,
-			&comp.CreatedAt,
-			&comp.HasStories,
-			&rUserID,
-			&rCompanionID,
-			&rAffinityScore,
-			&rCurrentMood,
-			&rLastInteractionAt,
-		)
+		comp, err := scanCompanion(rows)
 		if err != nil {
 			continue
 		}
-
-		// Map relationship if it exists
-		if rUserID.Valid {
-			rel.UserID = rUserID.String
-			rel.CompanionID = rCompanionID.String
-			rel.AffinityScore = int(rAffinityScore.Int64)
-			rel.CurrentMood = rCurrentMood.String
-			rel.LastInteractionAt = rLastInteractionAt.Time
-			comp.Relationship = &rel
-		}
-
 		companions = append(companions, comp)
 	}
 
@@ -96,33 +111,12 @@ func GetCompanions(c *gin.Context) {
 	})
 }
 
-// GetCompanionByID returns a specific companion with user affinity if authenticated
+// GetCompanionByID returns a specific companion with the user relationship if
+// authenticated, in the same shape as GetCompanions
 func GetCompanionByID(c *gin.Context) {
 	companionID := c.Param("id")
-	userID, _ := c.Get("user_id") // Optional auth
-
-	// Get companion details
-	compQuery := `
-		SELECT id, name, anime_source, archetype, avatar_url, 
-		       personality_traits, tags, system_prompt, mood, created_at
-		FROM companions
-		WHERE id = $1
-	`
-
-	var comp domain.Companion
-	err := db.DB.QueryRow(compQuery, companionID).Scan(
-		&comp.ID,
-		&comp.Name,
-		&comp.AnimeSource,
-		&comp.Archetype,
-		&comp.AvatarURL,
-		pq.Array(&comp.PersonalityTraits),
-		pq.Array(&comp.Tags),
-		&comp.SystemPrompt,
-		&comp.Mood,
-		&comp.CreatedAt,
-	)
 
+	comp, err := scanCompanion(db.DB.QueryRow(companionQuery+` WHERE c.id = $2`, optionalUserID(c), companionID))
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Companion not found"})
 		return
@@ -132,33 +126,5 @@ func GetCompanionByID(c *gin.Context) {
 		return
 	}
 
-	response := domain.CompanionWithAffinity{
-		Companion: comp,
-	}
-
-	// If user is authenticated, get their affinity
-	if userID != nil {
-		affinityQuery := `
-			SELECT user_id, companion_id, xp, level, last_interaction, created_at
-			FROM user_affinity
-			WHERE user_id = $1 AND companion_id = $2
-		`
-
-		var affinity domain.UserAffinity
-		err := db.DB.QueryRow(affinityQuery, userID, companionID).Scan(
-			&affinity.UserID,
-			&affinity.CompanionID,
-			&affinity.XP,
-			&affinity.Level,
-			&affinity.LastInteraction,
-			&affinity.CreatedAt,
-		)
-
-		if err == nil {
-			response.UserAffinity = &affinity
-		}
-		// If no affinity exists, that's fine - it means they haven't interacted yet
-	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, comp)
 }
@@ -1,25 +1,87 @@
 package handlers
 
 import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
 	"anikama-backend/pkg/db"
+	"anikama-backend/pkg/eth"
 	"database/sql"
+	"errors"
+	"log"
+	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// depositService verifies and credits deposits, set at startup (nil disables deposits)
+var depositService *service.DepositService
+
+// SetDepositService configures the deposit flow used by Deposit and GetDeposit
+func SetDepositService(svc *service.DepositService) {
+	depositService = svc
+}
+
+// respondDepositError maps deposit failures to responses
+func respondDepositError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWalletNotLinked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link a wallet before depositing"})
+	case errors.Is(err, service.ErrDepositExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Transaction already processed"})
+	case errors.Is(err, service.ErrTooManyPendingDeposits):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many deposits waiting for confirmation"})
+	case errors.Is(err, eth.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found on chain yet, try again once it has been broadcast"})
+	case errors.Is(err, eth.ErrInvalidHash):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction hash"})
+	case errors.Is(err, eth.ErrInvalidEthValue):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+	case errors.Is(err, service.ErrPackNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin pack not found"})
+	case errors.Is(err, service.ErrNoRate):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Coin pricing is not configured"})
+	case errors.Is(err, eth.ErrWrongRecipient), errors.Is(err, eth.ErrWrongSender),
+		errors.Is(err, eth.ErrAmountMismatch), errors.Is(err, eth.ErrTxFailed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		log.Printf("deposit failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to verify transaction"})
+	}
+}
+
 type DepositRequest struct {
-	TxHash string  `json:"tx_hash" binding:"required"`
-	Amount float64 `json:"amount" binding:"required"` // Amount in ETH
+	TxHash    string  `json:"tx_hash" binding:"required"`
+	AmountWei string  `json:"amount_wei"` // Exact amount in wei (preferred)
+	Amount    float64 `json:"amount"`     // Amount in ETH, used if amount_wei is empty
+	PackID    string  `json:"pack_id"`    // Optional coin pack; the amount must be its price
+	RateID    string  `json:"rate_id"`    // Rate from GET /economy/pricing the price was taken from
+}
+
+// amountWei returns the claimed amount in wei, preferring the exact amount_wei
+func (r *DepositRequest) amountWei() (*big.Int, error) {
+	if r.AmountWei != "" {
+		wei, ok := new(big.Int).SetString(r.AmountWei, 10)
+		if !ok {
+			return nil, eth.ErrInvalidEthValue
+		}
+		return wei, nil
+	}
+	return eth.ParseEther(strconv.FormatFloat(r.Amount, 'f', -1, 64))
 }
 
 type DepositResponse struct {
-	NewBalance int    `json:"new_balance"`
-	CoinsAdded int    `json:"coins_added"`
-	Message    string `json:"message"`
+	NewBalance int             `json:"new_balance"`
+	CoinsAdded int             `json:"coins_added"` // 0 until the deposit is confirmed
+	Message    string          `json:"message"`
+	Deposit    *domain.Deposit `json:"deposit"`
 }
 
-// Deposit handles the purchase of Hush Coins with ETH
+// Deposit handles the purchase of Hush Coins with ETH. Deposits that aren't
+// confirmed yet are recorded as pending (202) and credited by the deposit
+// worker; poll GET /economy/deposits/:tx_hash for the outcome.
 func Deposit(c *gin.Context) {
 	// Get User ID from context (Auth middleware)
 	userID, exists := c.Get("user_id")
@@ -34,72 +96,171 @@ func Deposit(c *gin.Context) {
 		return
 	}
 
-	// 1. Validate Transaction Hash (Prevent Replay)
-	var existingID string
-	err := db.DB.QueryRow("SELECT id FROM transactions WHERE tx_hash = $1", req.TxHash).Scan(&existingID)
-	if err == nil {
+	if depositService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Deposits are not available"})
+		return
+	}
+	txHash := strings.ToLower(req.TxHash)
+	ctx := c.Request.Context()
+
+	// 1. Validate Transaction Hash (Prevent Replay; failed deposits can be resubmitted)
+	var existingStatus string
+	err := db.DB.QueryRow("SELECT status FROM transactions WHERE tx_hash = $1", txHash).Scan(&existingStatus)
+	if err == nil && existingStatus != service.DepositFailed {
 		c.JSON(http.StatusConflict, gin.H{"error": "Transaction already processed"})
 		return
-	} else if err != sql.ErrNoRows {
+	} else if err != nil && err != sql.ErrNoRows {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check transaction"})
 		return
 	}
 
-	// 2. TODO: Verify Transaction on Blockchain
-	// reliable verification should happen here using an ETH client or checking the receipt.
-	// For this assessment, we trust the frontend hash but log the verify requirement.
-	// log.Printf("TODO: Verify tx %s on chain", req.TxHash)
-
-	// 3. Convert ETH to Hush Coins
-	// Rate: 0.001 ETH = 100 Hush Coins => 1 ETH = 100,000 Coins
-	conversionRate := 100000.0
-	coinsGranted := int(req.Amount * conversionRate)
-
-	if coinsGranted <= 0 {
+	// 2. Parse the claimed amount exactly (no float rounding in wei)
+	claimedWei, err := req.amountWei()
+	if err != nil || claimedWei.Sign() <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
 		return
 	}
 
-	// 4. Execute Transaction (Insert Record + Update Profile)
-	tx, err := db.DB.Begin()
+	var walletAddress string
+	err = db.DB.QueryRow(`SELECT COALESCE(wallet_address, '') FROM profiles WHERE id = $1`, userID).Scan(&walletAddress)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+	if walletAddress == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link a wallet before depositing"})
+		return
+	}
+
+	// 3. Price the deposit at the rate the client saw (pack price or custom amount)
+	quote, err := service.QuoteDeposit(ctx, db.DB, req.PackID, req.RateID, claimedWei)
+	if err != nil && !errors.Is(err, service.ErrPriceMismatch) {
+		respondDepositError(c, err)
 		return
 	}
-	defer tx.Rollback()
 
-	// Insert into transactions
-	_, err = tx.Exec(`
-		INSERT INTO transactions (user_id, tx_hash, amount_eth, coins_granted, status)
-		VALUES ($1, $2, $3, $4, 'confirmed')
-	`, userID, req.TxHash, req.Amount, coinsGranted)
+	// 4. Verify on chain and record (credited now if already confirmed, else pending).
+	// The ETH may already be sent, so a price mismatch is kept for review rather than rejected.
+	var deposit *domain.Deposit
+	if errors.Is(err, service.ErrPriceMismatch) {
+		deposit, err = depositService.SubmitForReview(ctx, userID.(string), txHash, walletAddress, claimedWei, req.PackID, err.Error())
+	} else {
+		deposit, err = depositService.Submit(ctx, userID.(string), txHash, walletAddress, claimedWei, quote)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		respondDepositError(c, err)
 		return
 	}
 
-	// Update profiles (increment hush_coins)
 	var newBalance int
-	err = tx.QueryRow(`
-		UPDATE profiles
-		SET hush_coins = COALESCE(hush_coins, 0) + $1
-		WHERE id = $2
-		RETURNING hush_coins
-	`, coinsGranted, userID).Scan(&newBalance)
+	err = db.DB.QueryRowContext(ctx, `SELECT COALESCE(hush_coins, 0) FROM profiles WHERE id = $1`, userID).Scan(&newBalance)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update balance"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balance"})
+		return
+	}
+
+	if deposit.Status == service.DepositNeedsReview {
+		c.JSON(http.StatusAccepted, DepositResponse{
+			NewBalance: newBalance,
+			Message:    "The amount doesn't match the pack price. Your deposit was recorded and will be reviewed.",
+			Deposit:    deposit,
+		})
 		return
 	}
 
-	// Commit
-	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+	if deposit.Status != service.DepositConfirmed {
+		c.JSON(http.StatusAccepted, DepositResponse{
+			NewBalance: newBalance,
+			Message:    "Deposit received! Hush Coins will be added once the transaction is confirmed.",
+			Deposit:    deposit,
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, DepositResponse{
 		NewBalance: newBalance,
-		CoinsAdded: coinsGranted,
+		CoinsAdded: deposit.Coins,
 		Message:    "Purchase successful! Hush Coins added.",
+		Deposit:    deposit,
 	})
 }
+
+// GetDeposit returns the status of one of the user's deposits
+func GetDeposit(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if depositService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Deposits are not available"})
+		return
+	}
+
+	deposit, err := depositService.Get(c.Request.Context(), userID.(string), strings.ToLower(c.Param("tx_hash")))
+	if errors.Is(err, service.ErrDepositNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deposit not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deposit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deposit": deposit})
+}
+
+const (
+	defaultLedgerPageSize = 50
+	maxLedgerPageSize     = 200
+)
+
+// GetLedger returns the user's Hush Coin history, newest first.
+// Query params: limit (default 50, max 200) and before (id of the last entry of the previous page).
+func GetLedger(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := defaultLedgerPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(n, maxLedgerPageSize)
+	}
+
+	var before int64
+	if v := c.Query("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		before = n
+	}
+
+	ctx := c.Request.Context()
+	entries, err := service.LedgerHistory(ctx, db.DB, userID.(string), before, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ledger"})
+		return
+	}
+
+	balance, err := service.Balance(ctx, db.DB, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balance"})
+		return
+	}
+
+	resp := domain.LedgerResponse{Entries: entries, Balance: balance}
+	if len(entries) == limit {
+		resp.NextBefore = entries[len(entries)-1].Seq
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
+	"anikama-backend/pkg/db"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GiftResponse is an InteractResponse plus what the gift cost
+type GiftResponse struct {
+	InteractResponse
+	GiftID     string `json:"gift_id"`
+	CoinsSpent int    `json:"coins_spent"`
+	Balance    int    `json:"balance"`
+}
+
+// GetGifts returns the gift catalog
+func GetGifts(c *gin.Context) {
+	gifts, err := service.ListGifts(c.Request.Context(), db.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch gifts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gifts": gifts,
+		"count": len(gifts),
+	})
+}
+
+// SendGift buys a gift for a companion with Hush Coins and applies how the
+// companion's personality takes it to the relationship
+func SendGift(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req domain.GiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	companionID := c.Param("id")
+	result, err := service.SendGift(c.Request.Context(), db.DB, userID.(string), companionID, req.GiftID)
+	switch {
+	case errors.Is(err, service.ErrCompanionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Companion not found"})
+		return
+	case errors.Is(err, service.ErrGiftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Gift not found"})
+		return
+	case errors.Is(err, service.ErrInsufficientCoins):
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":   "Not enough Hush Coins",
+			"cost":    result.Price,
+			"balance": result.Balance,
+		})
+		return
+	case err != nil:
+		log.Printf("Failed to send gift: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send gift"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GiftResponse{
+		InteractResponse: interactResponse(companionID, result.InteractionResult),
+		GiftID:           result.GiftID,
+		CoinsSpent:       result.Price,
+		Balance:          result.Balance,
+	})
+}
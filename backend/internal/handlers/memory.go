@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"anikama-backend/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryService keeps long-term companion memory, set at startup (nil disables memory)
+var memoryService *service.MemoryService
+
+// SetMemoryService configures the long-term memory used by the chat handlers
+func SetMemoryService(svc *service.MemoryService) {
+	memoryService = svc
+}
+
+// recallService finds relevant past exchanges, set at startup (nil disables recall)
+var recallService *service.RecallService
+
+// SetRecallService configures semantic recall used by the chat handlers
+func SetRecallService(svc *service.RecallService) {
+	recallService = svc
+}
+
+// GetCompanionMemory returns what a companion remembers about the current user
+func GetCompanionMemory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	companionID := c.Param("id")
+
+	if memoryService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Memory is not enabled"})
+		return
+	}
+
+	mem, err := memoryService.Get(c.Request.Context(), userID.(string), companionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch memory"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"memory": mem})
+}
+
+// DeleteCompanionMemory wipes what a companion remembers about the current user
+func DeleteCompanionMemory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	companionID := c.Param("id")
+
+	if memoryService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Memory is not enabled"})
+		return
+	}
+
+	if err := memoryService.Forget(c.Request.Context(), userID.(string), companionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to wipe memory"})
+		return
+	}
+
+	// Recalled exchanges are memories too
+	if recallService != nil {
+		if err := recallService.Forget(c.Request.Context(), userID.(string), companionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to wipe memory"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Memory wiped"})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
+	"anikama-backend/pkg/db"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPricing returns the current ETH to Hush Coin rate and coin pack prices
+func GetPricing(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rate, err := service.CurrentRate(ctx, db.DB, time.Now())
+	if errors.Is(err, service.ErrNoRate) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Coin pricing is not configured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pricing"})
+		return
+	}
+
+	packs, err := service.ListPacks(ctx, db.DB, rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coin packs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.PricingResponse{Rate: rate, Packs: packs})
+}
+
+// PublishCoinRate schedules a new ETH to Hush Coin rate (admin only)
+func PublishCoinRate(c *gin.Context) {
+	var req domain.PublishRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	effectiveFrom := time.Now()
+	if req.EffectiveFrom != nil {
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	rate, err := service.PublishRate(c.Request.Context(), db.DB, req.CoinsPerETH, effectiveFrom, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"rate": rate})
+}
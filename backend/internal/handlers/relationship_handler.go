@@ -3,6 +3,7 @@ package handlers
 import (
 	"anikama-backend/pkg/db"
 	"database/sql"
+	"log"
 	"net/http"
 	"time"
 
@@ -23,6 +24,10 @@ type InteractResponse struct {
 	NewMood          string `json:"new_mood"`
 	ToastMessage     string `json:"toast_message"`
 	ReactionVideoURL string `json:"reaction_video_url,omitempty"`
+	XP               int    `json:"xp"`
+	Level            int    `json:"level"`
+	XPGained         int    `json:"xp_gained"`
+	LeveledUp        bool   `json:"leveled_up"`
 }
 
 func Interact(c *gin.Context) {
@@ -51,107 +56,101 @@ func Interact(c *gin.Context) {
 		return
 	}
 
-	personalityType := "Deredere" // Default
+	personality := service.DefaultPersonality
 	if personalityTypeNull.Valid {
-		personalityType = personalityTypeNull.String
+		personality = service.PersonalityType(personalityTypeNull.String)
 	}
 
-	// 1b. Fetch Story Mood if StoryID is present
+	// 1b. Fetch Story Mood if StoryID is present; the story must be the companion's
 	storyMood := "neutral"
 	if req.StoryID != "" {
-		err := db.DB.QueryRow("SELECT mood FROM stories WHERE id = $1", req.StoryID).Scan(&storyMood)
-		if err != nil && err != sql.ErrNoRows {
-			// Log error but continue with neutral mood
-			// Log error but continue with neutral mood
+		err := db.DB.QueryRow(`
+			SELECT COALESCE(mood, 'neutral') FROM stories WHERE id = $1 AND companion_id = $2
+		`, req.StoryID, req.CompanionID).Scan(&storyMood)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch story mood: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch story"})
+			return
 		}
 	}
 
-	// 2. Fetch Current Relationship (if exists)
-	var currentScore int
-	var lastInteraction time.Time
-
-	// Default values
-	currentScore = 0
-	lastInteraction = time.Now().Add(-240 * time.Hour) // Long time ago
-
-	var dbScore sql.NullInt64
-	var dbLastInteraction sql.NullTime
+	// 2. Calculate the delta for this personality
+	delta := service.CalculateDelta(personality, service.ReactionType(req.Action), storyMood)
 
-	err = db.DB.QueryRow(`
-		SELECT affinity_score, last_interaction_at 
-		FROM relationships 
-		WHERE user_id = $1 AND companion_id = $2
-	`, userID, req.CompanionID).Scan(&dbScore, &dbLastInteraction)
-
-	if err == sql.ErrNoRows {
-		// No relationship exists, create one
-		// No relationship exists, create one
-		// Default values are already set: currentScore = 0, lastInteraction = long ago
-		// We can proceed with these defaults, the INSERT/UPSERT at the end will save it.
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch relationship"})
+	ctx := c.Request.Context()
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer tx.Rollback()
 
-	if dbScore.Valid {
-		currentScore = int(dbScore.Int64)
-	}
-	if dbLastInteraction.Valid {
-		lastInteraction = dbLastInteraction.Time
+	// 3. Viewing a story earns XP the first time only
+	xp := 0
+	if req.StoryID != "" {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO story_views (user_id, story_id) VALUES ($1, $2)
+			ON CONFLICT (user_id, story_id) DO NOTHING
+		`, userID, req.StoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record story view"})
+			return
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			xp = service.GetXPForAction(service.XPActionViewStory)
+		}
 	}
 
-	// 3. Calculate New State
-	companionPersonality := service.PersonalityType(personalityType)
-	delta := service.CalculateDelta(companionPersonality, service.ReactionType(req.Action), storyMood)
+	// 4. Apply it to the relationship
 
-	newScore := currentScore + delta
-	// Clamp score between -100 and 100
-	if newScore > 100 {
-		newScore = 100
-	} else if newScore < -100 {
-		newScore = -100
+	result, err := service.ApplyAffinityDelta(ctx, tx, userID.(string), req.CompanionID, personality, delta, xp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update relationship"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update relationship"})
+		return
 	}
 
-	newMood := service.CalculateMood(newScore, lastInteraction)
-
-	toastMsg := service.GenerateToastMessage(companionPersonality, newMood, delta)
+	c.JSON(http.StatusOK, interactResponse(req.CompanionID, result))
+}
 
-	// 5. Fetch Reaction Video if applicable
-	var reactionVideoURL string
-	if delta != 0 {
-		var happyURL, sadURL string
-		err := db.DB.QueryRow("SELECT happy_reaction_url, sad_reaction_url FROM reactions WHERE companion_id = $1", req.CompanionID).Scan(&happyURL, &sadURL)
-		if err == nil {
-			if delta > 0 {
-				reactionVideoURL = happyURL
-			} else {
-				reactionVideoURL = sadURL
-			}
-		}
+// interactResponse builds the response for a change to a relationship
+func interactResponse(companionID string, result *service.InteractionResult) InteractResponse {
+	return InteractResponse{
+		NewScore:         result.NewScore,
+		Delta:            result.Delta,
+		NewMood:          string(result.NewMood),
+		ToastMessage:     result.ToastMessage,
+		ReactionVideoURL: reactionVideoURL(companionID, result.Delta),
+		XP:               result.XP,
+		Level:            result.Level,
+		XPGained:         result.XPGained,
+		LeveledUp:        result.LeveledUp,
 	}
+}
 
-	// 4. Upsert Relationship
-	_, err = db.DB.Exec(`
-		INSERT INTO relationships (user_id, companion_id, affinity_score, current_mood, last_interaction_at)
-		VALUES ($1, $2, $3, $4, NOW())
-		ON CONFLICT (user_id, companion_id) 
-		DO UPDATE SET affinity_score = $3, current_mood = $4, last_interaction_at = NOW()
-	`, userID, req.CompanionID, newScore, string(newMood))
+// reactionVideoURL returns the companion's happy or sad reaction video for a
+// delta, or "" if there was no change or no reactions are set up
+func reactionVideoURL(companionID string, delta int) string {
+	if delta == 0 {
+		return ""
+	}
 
+	var happyURL, sadURL string
+	err := db.DB.QueryRow("SELECT happy_reaction_url, sad_reaction_url FROM reactions WHERE companion_id = $1", companionID).Scan(&happyURL, &sadURL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update relationship"})
-		return
+		return ""
 	}
-
-	resp := InteractResponse{
-		NewScore:         newScore,
-		Delta:            delta,
-		NewMood:          string(newMood),
-		ToastMessage:     toastMsg,
-		ReactionVideoURL: reactionVideoURL,
+	if delta > 0 {
+		return happyURL
 	}
-
-	c.JSON(http.StatusOK, resp)
+	return sadURL
 }
 
 // GetRelationship returns the relationship status between current user and a companion
@@ -169,15 +168,15 @@ func GetRelationship(c *gin.Context) {
 		return
 	}
 
-	var affinityScore int
+	var affinityScore, xp, level int
 	var currentMood string
 	var lastInteraction time.Time
 
 	err := db.DB.QueryRow(`
-		SELECT affinity_score, current_mood, last_interaction_at 
+		SELECT affinity_score, current_mood, xp, level, last_interaction_at 
 		FROM relationships 
 		WHERE user_id = $1 AND companion_id = $2
-	`, userID, companionID).Scan(&affinityScore, &currentMood, &lastInteraction)
+	`, userID, companionID).Scan(&affinityScore, &currentMood, &xp, &level, &lastInteraction)
 
 	if err == sql.ErrNoRows {
 		// Return friendly empty state for frontend to handle (or 404, but json is nicer)
@@ -186,6 +185,8 @@ func GetRelationship(c *gin.Context) {
 			"found":          false,
 			"affinity_score": 0,
 			"current_mood":   "neutral",
+			"xp":             0,
+			"level":          service.CalculateLevel(0),
 		})
 		return
 	} else if err != nil {
@@ -197,6 +198,8 @@ func GetRelationship(c *gin.Context) {
 		"found":               true,
 		"affinity_score":      affinityScore,
 		"current_mood":        currentMood,
+		"xp":                  xp,
+		"level":               level,
 		"last_interaction_at": lastInteraction,
 	})
 }
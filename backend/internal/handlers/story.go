@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
 	"anikama-backend/pkg/db"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,25 +13,13 @@ import (
 
 // GetStories returns all active stories grouped by companion
 func GetStories(c *gin.Context) {
-	// 1. Fetch User Tier
-	userID, exists := c.Get("user_id")
-	userTier := "free" // Default to free if auth fails or not provided (though auth middleware should handle it)
-
-	if exists {
-		// Assuming userID is the token or ID.
-		// Ideally we fetch the user from DB to get fresh tier.
-		// For now, let's query the user tier.
-		var tier string
-		err := db.DB.QueryRow("SELECT tier FROM profiles WHERE id = $1", userID).Scan(&tier)
-		if err == nil {
-			userTier = tier
-		}
-	}
+	// 1. Fetch what the caller is entitled to see (anonymous callers are free tier)
+	ent := service.LoadEntitlements(c.Request.Context(), db.DB, c.GetString("user_id"))
 
 	query := `
 		SELECT 
 			s.id, s.companion_id, s.media_url, s.media_type, 
-			s.duration, s.order_index, s.mood, s.is_premium, s.created_at,
+			s.duration, s.order_index, s.mood, s.is_premium, s.coin_price, s.created_at,
 			co.name, co.avatar_url
 		FROM stories s
 		JOIN companions co ON s.companion_id = co.id
@@ -58,6 +49,7 @@ func GetStories(c *gin.Context) {
 			&story.OrderIndex,
 			&story.Mood,
 			&story.IsPremium,
+			&story.CoinPrice,
 			&story.CreatedAt,
 			&companionName,
 			&avatarURL,
@@ -67,12 +59,7 @@ func GetStories(c *gin.Context) {
 		}
 
 		// Apply Locking Logic
-		if story.IsPremium && userTier != "premium" {
-			story.IsLocked = true
-			story.MediaURL = "" // Redact URL
-		} else {
-			story.IsLocked = false
-		}
+		lockStory(&story, ent)
 
 		// If this companion isn't in the map yet, create entry
 		if _, exists := storiesMap[story.CompanionID]; !exists {
@@ -107,22 +94,13 @@ func GetStories(c *gin.Context) {
 func GetStoryByCompanionID(c *gin.Context) {
 	companionID := c.Param("companionId")
 
-	// 1. Fetch User Tier
-	userID, exists := c.Get("user_id")
-	userTier := "free"
-
-	if exists {
-		var tier string
-		err := db.DB.QueryRow("SELECT tier FROM profiles WHERE id = $1", userID).Scan(&tier)
-		if err == nil {
-			userTier = tier
-		}
-	}
+	// 1. Fetch what the caller is entitled to see (anonymous callers are free tier)
+	ent := service.LoadEntitlements(c.Request.Context(), db.DB, c.GetString("user_id"))
 
 	query := `
 		SELECT 
 			s.id, s.companion_id, s.media_url, s.media_type, 
-			s.duration, s.order_index, s.mood, s.is_premium, s.created_at,
+			s.duration, s.order_index, s.mood, s.is_premium, s.coin_price, s.created_at,
 			co.name, co.avatar_url
 		FROM stories s
 		JOIN companions co ON s.companion_id = co.id
@@ -151,6 +129,7 @@ func GetStoryByCompanionID(c *gin.Context) {
 			&story.OrderIndex,
 			&story.Mood,
 			&story.IsPremium,
+			&story.CoinPrice,
 			&story.CreatedAt,
 			&companionName,
 			&avatarURL,
@@ -160,12 +139,7 @@ func GetStoryByCompanionID(c *gin.Context) {
 		}
 
 		// Apply Locking Logic
-		if story.IsPremium && userTier != "premium" {
-			story.IsLocked = true
-			story.MediaURL = "" // Redact URL
-		} else {
-			story.IsLocked = false
-		}
+		lockStory(&story, ent)
 
 		stories = append(stories, story)
 	}
@@ -177,3 +151,61 @@ func GetStoryByCompanionID(c *gin.Context) {
 		"count":   len(stories),
 	})
 }
+
+// lockStory locks and redacts premium stories the caller isn't entitled to
+func lockStory(story *domain.Story, ent *service.Entitlements) {
+	if !ent.CanViewStory(story.ID, story.IsPremium) {
+		story.IsLocked = true
+		story.MediaURL = "" // Redact URL
+		return
+	}
+	story.IsLocked = false
+	story.CoinPrice = 0
+}
+
+// UnlockStory buys a single premium story with Hush Coins
+func UnlockStory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Premium covers every story already
+	tier, err := service.ResolveTier(c.Request.Context(), db.DB, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tier"})
+		return
+	}
+	if tier == service.TierPremium {
+		c.JSON(http.StatusConflict, gin.H{"error": "Story is already unlocked"})
+		return
+	}
+
+	unlock, err := service.UnlockStory(c.Request.Context(), db.DB, userID.(string), c.Param("id"))
+	switch {
+	case errors.Is(err, service.ErrStoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+		return
+	case errors.Is(err, service.ErrStoryNotLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": "Story is already unlocked"})
+		return
+	case errors.Is(err, service.ErrInsufficientCoins):
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":   "Not enough Hush Coins",
+			"cost":    unlock.Price,
+			"balance": unlock.Balance,
+		})
+		return
+	case err != nil:
+		log.Printf("Failed to unlock story: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock story"})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.StoryUnlockResponse{
+		StoryID:    unlock.StoryID,
+		CoinsSpent: unlock.Price,
+		Balance:    unlock.Balance,
+	})
+}
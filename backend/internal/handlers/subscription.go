@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
+	"anikama-backend/pkg/db"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSubscription returns the caller's tier, subscription and the plans on sale
+func GetSubscription(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	// 1. Resolve the tier first so a lapsed subscription is expired before it's shown
+	tier, err := service.ResolveTier(ctx, db.DB, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tier"})
+		return
+	}
+
+	// 2. Get the subscription (nil if the user never subscribed)
+	sub, err := service.GetSubscription(ctx, db.DB, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscription"})
+		return
+	}
+
+	// 3. Get the plans
+	plans, err := service.ListPlans(ctx, db.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscription plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SubscriptionResponse{
+		Tier:         tier,
+		Subscription: sub,
+		Plans:        plans,
+	})
+}
+
+// Subscribe buys or renews premium with Hush Coins
+func Subscribe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req domain.SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purchase, err := service.PurchaseSubscription(c.Request.Context(), db.DB, userID.(string), req.PlanID)
+	switch {
+	case errors.Is(err, service.ErrPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription plan not found"})
+		return
+	case errors.Is(err, service.ErrInsufficientCoins):
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":   "Not enough Hush Coins",
+			"cost":    purchase.Price,
+			"balance": purchase.Balance,
+		})
+		return
+	case err != nil:
+		log.Printf("Failed to purchase subscription: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purchase subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.SubscribeResponse{
+		Tier:         service.TierPremium,
+		Subscription: purchase.Subscription,
+		CoinsSpent:   purchase.Price,
+		Balance:      purchase.Balance,
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"anikama-backend/internal/domain"
+	"anikama-backend/internal/service"
 	"anikama-backend/pkg/db"
 	"net/http"
 
@@ -17,7 +18,7 @@ func GetCurrentUser(c *gin.Context) {
 	}
 
 	query := `
-		SELECT id, username, tier, COALESCE(hush_coins, 0), created_at, updated_at
+		SELECT id, username, tier, COALESCE(hush_coins, 0), COALESCE(wallet_address, ''), created_at, updated_at
 		FROM profiles
 		WHERE id = $1
 	`
@@ -28,6 +29,7 @@ func GetCurrentUser(c *gin.Context) {
 		&user.Username,
 		&user.Tier,
 		&user.HushCoins,
+		&user.WalletAddress,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -37,6 +39,11 @@ func GetCurrentUser(c *gin.Context) {
 		return
 	}
 
+	// Report the effective tier, expiring a lapsed subscription
+	if tier, err := service.ResolveTier(c.Request.Context(), db.DB, user.ID); err == nil {
+		user.Tier = tier
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"user": user,
 	})
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"anikama-backend/pkg/db"
+	"anikama-backend/pkg/eth"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Wallet-link challenges must be signed within this window
+const walletChallengeTTL = 10 * time.Minute
+
+type LinkWalletRequest struct {
+	Address   string `json:"address" binding:"required"`
+	Signature string `json:"signature" binding:"required"` // personal_sign signature of the challenge message
+}
+
+// walletChallengeMessage is the EIP-191 message the user signs to prove wallet ownership
+func walletChallengeMessage(nonce string, issuedAt time.Time) string {
+	return fmt.Sprintf("Anikama wants you to link this wallet to your account.\n\nNonce: %s\nIssued At: %s",
+		nonce, issuedAt.UTC().Format(time.RFC3339))
+}
+
+// GetWalletChallenge issues a fresh nonce and the message to sign with the wallet
+func GetWalletChallenge(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate nonce"})
+		return
+	}
+	nonce := hex.EncodeToString(buf)
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	expiresAt := issuedAt.Add(walletChallengeTTL)
+
+	// Replaces any earlier challenge
+	_, err := db.DB.Exec(`
+		INSERT INTO wallet_challenges (user_id, nonce, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET nonce = EXCLUDED.nonce, issued_at = EXCLUDED.issued_at, expires_at = EXCLUDED.expires_at
+	`, userID, nonce, issuedAt, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    walletChallengeMessage(nonce, issuedAt),
+		"nonce":      nonce,
+		"expires_at": expiresAt,
+	})
+}
+
+// LinkWallet verifies the signed challenge and links the signing wallet to the user
+func LinkWallet(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req LinkWalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !eth.IsAddress(req.Address) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address"})
+		return
+	}
+	address := strings.ToLower(req.Address)
+
+	ctx := c.Request.Context()
+
+	// 1. Consume the challenge (one attempt per challenge). It is deleted in its
+	// own statement, so it stays consumed whatever happens next.
+	var nonce string
+	var issuedAt, expiresAt time.Time
+	err := db.DB.QueryRowContext(ctx, `
+		DELETE FROM wallet_challenges WHERE user_id = $1
+		RETURNING nonce, issued_at, expires_at
+	`, userID).Scan(&nonce, &issuedAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pending challenge, request a new one"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch challenge"})
+		return
+	}
+	if time.Now().After(expiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge expired, request a new one"})
+		return
+	}
+
+	// 2. Recover the signer and check it is the claimed wallet
+	signer, err := eth.RecoverPersonalSigner(walletChallengeMessage(nonce, issuedAt), req.Signature)
+	if err != nil || signer != address {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Signature does not match wallet"})
+		return
+	}
+
+	// 3. Link it
+	_, err = db.DB.ExecContext(ctx, `
+		UPDATE profiles SET wallet_address = $1, updated_at = NOW() WHERE id = $2
+	`, address, userID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Wallet is already linked to another account"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link wallet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wallet_address": address})
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultDepositInterval is how often pending deposits are re-checked
+const DefaultDepositInterval = 15 * time.Second
+
+// RunDepositWorker polls pending deposits until ctx is cancelled, crediting
+// confirmed ones and failing invalid or timed out ones
+func RunDepositWorker(ctx context.Context, deposits *service.DepositService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := deposits.ProcessPending(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("deposit worker: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"log"
+	"time"
+)
+
+// DefaultIdempotencyCleanupInterval is how often expired idempotency keys are deleted
+const DefaultIdempotencyCleanupInterval = time.Hour
+
+// RunIdempotencyCleanup deletes expired idempotency records until ctx is cancelled
+func RunIdempotencyCleanup(ctx context.Context, store service.IdempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := store.DeleteExpired(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("idempotency cleanup: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
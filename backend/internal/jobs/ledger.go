@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// DefaultReconcileInterval is how often cached balances are checked against the ledger
+const DefaultReconcileInterval = time.Hour
+
+// RunLedgerReconciliation resets cached Hush Coin balances that drifted from
+// the ledger until ctx is cancelled. Drift means something bypassed the
+// ledger, so each case is logged as an alert.
+func RunLedgerReconciliation(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		drifted, err := service.ReconcileBalances(ctx, db)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("ledger reconciliation: %v", err)
+		}
+		for _, d := range drifted {
+			log.Printf("🚨 ledger drift: user %s had %d Hush Coins cached but %d in the ledger; reset to the ledger", d.UserID, d.Cached, d.Ledger)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// DefaultDecayInterval is how often idle relationships are decayed
+const DefaultDecayInterval = time.Hour
+
+// RunRelationshipDecay decays neglected relationships and recomputes their
+// moods until ctx is cancelled
+func RunRelationshipDecay(ctx context.Context, db *sql.DB, cfg service.DecayConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := service.DecayRelationships(ctx, db, cfg, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("relationship decay: %v", err)
+		} else if n > 0 {
+			log.Printf("relationship decay: updated %d relationships", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultRulesReloadInterval is how often the personality rules file is checked for changes
+const DefaultRulesReloadInterval = 10 * time.Second
+
+// WatchRules reloads the personality rules file whenever its modification
+// time changes, until ctx is cancelled. An invalid file is logged and the
+// rules in use are kept.
+func WatchRules(ctx context.Context, path string, interval time.Duration) {
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("personality rules: %v", err)
+			continue
+		}
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+
+		rules, err := service.LoadRulesFile(path)
+		if err != nil {
+			log.Printf("personality rules not reloaded: %v", err)
+			continue
+		}
+		service.SetRules(rules)
+		log.Printf("personality rules reloaded from %s", path)
+	}
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// DefaultSubscriptionInterval is how often lapsed subscriptions are expired
+const DefaultSubscriptionInterval = 10 * time.Minute
+
+// RunSubscriptionExpiry downgrades users whose subscription is past its grace
+// period until ctx is cancelled. Tier checks also expire on read, so this only
+// keeps profiles.tier accurate for users who aren't active.
+func RunSubscriptionExpiry(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := service.ExpireSubscriptions(ctx, db)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("subscription expiry: %v", err)
+		} else if n > 0 {
+			log.Printf("subscription expiry: downgraded %d users", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
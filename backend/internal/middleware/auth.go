@@ -1,17 +1,159 @@
 package middleware
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims are the Supabase access token claims the backend relies on
+type Claims struct {
+	Email       string                 `json:"email"`
+	Role        string                 `json:"role"` // "authenticated", "anon" or "service_role"
+	AppMetadata map[string]interface{} `json:"app_metadata"`
+	jwt.RegisteredClaims
+}
+
+// AppRole returns the application-level role stored in app_metadata (e.g. "admin")
+func (c *Claims) AppRole() string {
+	if role, ok := c.AppMetadata["role"].(string); ok {
+		return role
+	}
+	return ""
+}
+
+var (
+	errMissingSubject = errors.New("token has no subject")
+	errAnonymousToken = errors.New("anonymous tokens are not accepted")
+)
+
+// tokenVerifier checks Supabase access tokens. HS256 tokens are verified with
+// JWT_SECRET; RS256/ES256 tokens are verified against the project's JWKS.
+type tokenVerifier struct {
+	secret   []byte
+	jwks     *jwksCache
+	audience string
+	issuer   string
+}
+
+var (
+	verifierOnce    sync.Once
+	defaultVerifier *tokenVerifier
+)
+
+// sharedVerifier builds the verifier from the environment on first use so
+// AuthMiddleware and OptionalAuthMiddleware use the same keys and JWKS cache
+func sharedVerifier() *tokenVerifier {
+	verifierOnce.Do(func() {
+		defaultVerifier = newTokenVerifierFromEnv()
+	})
+	return defaultVerifier
+}
+
+func newTokenVerifierFromEnv() *tokenVerifier {
+	v := &tokenVerifier{
+		secret:   []byte(os.Getenv("JWT_SECRET")),
+		audience: os.Getenv("JWT_AUDIENCE"),
+		issuer:   os.Getenv("JWT_ISSUER"),
+	}
+	if v.audience == "" {
+		v.audience = "authenticated"
+	}
+
+	jwksURL := os.Getenv("SUPABASE_JWKS_URL")
+	if jwksURL == "" {
+		if supabaseURL := os.Getenv("SUPABASE_URL"); supabaseURL != "" {
+			jwksURL = strings.TrimRight(supabaseURL, "/") + "/auth/v1/.well-known/jwks.json"
+		}
+	}
+	if jwksURL != "" {
+		v.jwks = newJWKSCache(jwksURL)
+	}
+
+	if len(v.secret) == 0 && v.jwks == nil {
+		log.Println("⚠️  Neither JWT_SECRET nor SUPABASE_URL/SUPABASE_JWKS_URL is set, all tokens will be rejected")
+	}
+
+	return v
+}
+
+// Verify parses the token, checks its signature and validates exp, nbf, aud and iss
+func (v *tokenVerifier) Verify(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+		jwt.WithAudience(v.audience),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, v.keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errMissingSubject
+	}
+	if claims.Role == "anon" {
+		return nil, errAnonymousToken
+	}
+
+	return claims, nil
+}
+
+func (v *tokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(v.secret) == 0 {
+			return nil, fmt.Errorf("JWT_SECRET is not configured")
+		}
+		return v.secret, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		if v.jwks == nil {
+			return nil, fmt.Errorf("JWKS verification is not configured")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return v.jwks.Key(kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(authHeader string) (string, bool) {
+	parts := strings.Fields(authHeader)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// setClaims exposes the verified identity and role claims on the gin context
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set("user_id", claims.Subject)
+	c.Set("user_email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("app_role", claims.AppRole())
+	c.Set("claims", claims)
+}
+
 // AuthMiddleware validates the JWT token from Supabase
-// It extracts the user ID and attaches it to the context
+// It extracts the user ID and role claims and attaches them to the context
 func AuthMiddleware() gin.HandlerFunc {
+	verifier := sharedVerifier()
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -21,67 +163,58 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		token, ok := bearerToken(authHeader)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
 			c.Abort()
 			return
 		}
 
-		token := parts[1]
-
-		// TODO: Validate JWT token with Supabase
-		// For now, we'll create a placeholder
-		// In production, you would:
-		// 1. Verify the JWT signature using Supabase JWT secret
-		// 2. Extract the user ID from the token claims
-		// 3. Optionally check token expiration
-
-		// Placeholder: Use the token as user ID directly for development
-		// in production, you would parse the JWT and verify signature
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
+			} else {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			}
 			c.Abort()
 			return
 		}
 
-		// Try to extract user ID from JWT if it looks like one
-		userID := token
-		if strings.Count(token, ".") == 2 {
-			parts := strings.Split(token, ".")
-			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
-			if err == nil {
-				var claims map[string]interface{}
-				if err := json.Unmarshal(payload, &claims); err == nil {
-					if sub, ok := claims["sub"].(string); ok {
-						userID = sub
-					}
-				}
-			}
-		}
-
-		// Set user ID in context
-		c.Set("user_id", userID)
+		setClaims(c, claims)
 
 		c.Next()
 	}
 }
 
 // OptionalAuthMiddleware is similar to AuthMiddleware but doesn't abort if no auth
-// It just sets the user_id if available
+// It sets the verified user_id and role claims when a valid token is present and
+// otherwise lets the request through anonymously
 func OptionalAuthMiddleware() gin.HandlerFunc {
+	verifier := sharedVerifier()
+
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			parts := strings.Split(authHeader, " ")
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				token := parts[1]
-				if token != "" {
-					// Set user ID if valid (placeholder)
-					c.Set("user_id", "placeholder-user-id")
-				}
+		if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+			if claims, err := verifier.Verify(token); err == nil {
+				setClaims(c, claims)
 			}
 		}
 		c.Next()
 	}
 }
+
+// RequireAppRole only lets through users whose app_metadata role is one of
+// roles. It must run after AuthMiddleware.
+func RequireAppRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appRole := c.GetString("app_role")
+		for _, role := range roles {
+			if appRole == role {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
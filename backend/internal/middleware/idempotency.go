@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"anikama-backend/internal/service"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header clients send to make a retry safe
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long responses are kept for replay
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+
+	// Context key set by handlers whose response must not be replayed
+	skipIdempotencyKey = "idempotency_skip"
+)
+
+var (
+	idempotencyStore service.IdempotencyStore
+	idempotencyTTL   = DefaultIdempotencyTTL
+
+	// How often a running request renews its lease, well within IdempotencyLease
+	idempotencyRenewInterval = service.IdempotencyLease / 3
+)
+
+// SetIdempotencyStore configures where Idempotency replays responses from.
+// Without a store the header is ignored.
+func SetIdempotencyStore(store service.IdempotencyStore, ttl time.Duration) {
+	idempotencyStore = store
+	idempotencyTTL = ttl
+}
+
+// SkipIdempotentReplay keeps the current response out of the idempotency
+// store, e.g. a placeholder sent because a dependency was down, so a retry
+// with the same key runs the request again
+func SkipIdempotentReplay(c *gin.Context) {
+	c.Set(skipIdempotencyKey, true)
+}
+
+// Idempotency makes mutating requests that carry an Idempotency-Key header
+// safe to retry: the first request runs and its response is stored for the
+// TTL; retries with the same key replay it without running the handler again.
+// Keys are scoped to the user, so it must run after AuthMiddleware. Reusing a
+// key for a different request is rejected, as is a retry while the first is
+// still running; the running request renews its lease, so a retry only takes
+// the key over once the server that held it has died. Server errors and
+// responses marked with SkipIdempotentReplay are not stored so the request can
+// be retried.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		userID := c.GetString("user_id")
+		if key == "" || userID == "" || idempotencyStore == nil || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		// 1. Fingerprint the request so a reused key can't replay another request's response
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body)
+
+		// 2. Claim the key, or replay what the first request returned
+		ctx := context.WithoutCancel(c.Request.Context())
+		store := idempotencyStore
+		existing, lease, err := store.Reserve(ctx, userID, key, fingerprint, idempotencyTTL)
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case existing.StatusCode == 0:
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		// 3. Run the request, recording the response and holding the key
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		stored := false
+		defer func() {
+			if !stored {
+				if err := store.Release(ctx, userID, key, lease); err != nil {
+					log.Printf("idempotency: %v", err)
+				}
+			}
+		}()
+		stopRenewing := renewIdempotencyLease(ctx, store, userID, key, lease)
+		defer stopRenewing()
+
+		c.Next()
+		stopRenewing()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError || c.GetBool(skipIdempotencyKey) {
+			return
+		}
+		if err := store.Complete(ctx, userID, key, lease, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("idempotency: %v", err)
+			return
+		}
+		stored = true
+	}
+}
+
+// renewIdempotencyLease renews the lease in the background until the returned
+// func is called, which waits for renewal to stop. It gives up once the lease
+// is lost.
+func renewIdempotencyLease(ctx context.Context, store service.IdempotencyStore, userID, key, lease string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.Renew(ctx, userID, key, lease); err != nil {
+					log.Printf("idempotency: %v", err)
+					if errors.Is(err, service.ErrIdempotencyLeaseLost) {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// isMutating reports whether requests with the method change state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint hashes what identifies a request: method, path, query and body
+func requestFingerprint(method, path, query string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "?" + query + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
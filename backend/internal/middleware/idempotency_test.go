@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"anikama-backend/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newIdempotentRouter serves POST /things, counting how often the handler runs.
+// The handler answers with the given status, and skips storage when asked to.
+func newIdempotentRouter(t *testing.T, runs *int, status int, skip bool) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	SetIdempotencyStore(service.NewInMemoryIdempotencyStore(), time.Hour)
+	t.Cleanup(func() { SetIdempotencyStore(nil, DefaultIdempotencyTTL) })
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", "user-1") }, Idempotency())
+	r.POST("/things", func(c *gin.Context) {
+		*runs++
+		if skip {
+			SkipIdempotentReplay(c)
+		}
+		c.JSON(status, gin.H{"run": *runs})
+	})
+	return r
+}
+
+func send(r *gin.Engine, target, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	var runs int
+	r := newIdempotentRouter(t, &runs, http.StatusCreated, false)
+
+	first := send(r, "/things", "key-1", `{"a":1}`)
+	retry := send(r, "/things", "key-1", `{"a":1}`)
+
+	if runs != 1 {
+		t.Fatalf("handler ran %d times, want 1", runs)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry is missing %s", IdempotentReplayedHeader)
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("first response must not be marked as replayed")
+	}
+}
+
+func TestIdempotencyRejectsKeyReuse(t *testing.T) {
+	var runs int
+	r := newIdempotentRouter(t, &runs, http.StatusOK, false)
+
+	send(r, "/things", "key-1", `{"a":1}`)
+
+	for _, tt := range []struct {
+		name, target, body string
+	}{
+		{"different body", "/things", `{"a":2}`},
+		{"different query", "/things?force=true", `{"a":1}`},
+	} {
+		if w := send(r, tt.target, "key-1", tt.body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want 422", tt.name, w.Code)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("handler ran %d times, want 1", runs)
+	}
+}
+
+func TestIdempotencyRejectsRetryInProgress(t *testing.T) {
+	var runs int
+	r := newIdempotentRouter(t, &runs, http.StatusOK, false)
+
+	fingerprint := requestFingerprint(http.MethodPost, "/things", "", []byte(`{"a":1}`))
+	if _, _, err := idempotencyStore.Reserve(context.Background(), "user-1", "key-1", fingerprint, time.Hour); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	if w := send(r, "/things", "key-1", `{"a":1}`); w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", w.Code)
+	}
+	if runs != 0 {
+		t.Errorf("handler ran %d times, want 0", runs)
+	}
+}
+
+// renewCounter counts lease renewals
+type renewCounter struct {
+	service.IdempotencyStore
+	renewals int32
+}
+
+func (r *renewCounter) Renew(ctx context.Context, userID, key, lease string) error {
+	atomic.AddInt32(&r.renewals, 1)
+	return r.IdempotencyStore.Renew(ctx, userID, key, lease)
+}
+
+func TestIdempotencyRenewsLeaseWhileRunning(t *testing.T) {
+	var runs int
+	r := newIdempotentRouter(t, &runs, http.StatusOK, false)
+	store := &renewCounter{IdempotencyStore: idempotencyStore}
+	SetIdempotencyStore(store, time.Hour)
+
+	interval := idempotencyRenewInterval
+	idempotencyRenewInterval = time.Millisecond
+	t.Cleanup(func() { idempotencyRenewInterval = interval })
+
+	r.POST("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	send(r, "/slow", "key-1", `{}`)
+	if atomic.LoadInt32(&store.renewals) == 0 {
+		t.Fatalf("lease was never renewed")
+	}
+	renewals := atomic.LoadInt32(&store.renewals)
+	time.Sleep(5 * time.Millisecond)
+	if atomic.LoadInt32(&store.renewals) != renewals {
+		t.Errorf("lease still renewed after the request finished")
+	}
+	if retry := send(r, "/slow", "key-1", `{}`); retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry was not replayed")
+	}
+}
+
+func TestIdempotencyDoesNotStoreRetryableResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		skip   bool
+	}{
+		{"server error", http.StatusServiceUnavailable, false},
+		{"skipped", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs int
+			r := newIdempotentRouter(t, &runs, tt.status, tt.skip)
+
+			send(r, "/things", "key-1", `{"a":1}`)
+			retry := send(r, "/things", "key-1", `{"a":1}`)
+
+			if runs != 2 {
+				t.Errorf("handler ran %d times, want 2", runs)
+			}
+			if retry.Header().Get(IdempotentReplayedHeader) != "" {
+				t.Errorf("retry was replayed")
+			}
+		})
+	}
+}
+
+func TestIdempotencyIgnoresRequestsWithoutKey(t *testing.T) {
+	var runs int
+	r := newIdempotentRouter(t, &runs, http.StatusOK, false)
+
+	send(r, "/things", "", `{"a":1}`)
+	send(r, "/things", "", `{"a":1}`)
+
+	if runs != 2 {
+		t.Errorf("handler ran %d times, want 2", runs)
+	}
+}
@@ -21,7 +21,8 @@ func SetupRouter() *gin.Engine {
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/register", handlers.Register)
-			// Login is handled by Supabase on frontend
+			auth.POST("/login", handlers.Login)
+			auth.POST("/refresh", handlers.Refresh)
 		}
 
 		// Public companion routes
@@ -32,18 +33,35 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// Public stories route
-		v1.GET("/stories", handlers.GetStories)
-		v1.GET("/story/:companionId", handlers.GetStoryByCompanionID)
+		v1.GET("/stories", middleware.OptionalAuthMiddleware(), handlers.GetStories)
+		v1.GET("/story/:companionId", middleware.OptionalAuthMiddleware(), handlers.GetStoryByCompanionID)
+		v1.GET("/economy/pricing", handlers.GetPricing)
+		v1.GET("/gifts", handlers.GetGifts)
 
 		// Protected routes (require authentication)
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(), middleware.Idempotency())
 		{
 			// Chat endpoint (requires auth)
 			protected.POST("/chat", handlers.Chat)
+			protected.POST("/chat/stream", handlers.ChatStream)
 			protected.GET("/chats", handlers.GetChats) // List of chats
 			protected.GET("/chat/:companion_id/history", handlers.GetChatHistory)
 
+			// Companion memory endpoints
+			protected.GET("/companions/:id/memory", handlers.GetCompanionMemory)
+			protected.DELETE("/companions/:id/memory", handlers.DeleteCompanionMemory)
+
+			// Premium subscription
+			protected.GET("/subscription", handlers.GetSubscription)
+			protected.POST("/subscription", handlers.Subscribe)
+
+			// Story purchases
+			protected.POST("/stories/:id/unlock", handlers.UnlockStory)
+
+			// Gifts for companions
+			protected.POST("/companions/:id/gift", handlers.SendGift)
+
 			// Interaction endpoint (requires auth)
 			protected.POST("/interact", handlers.Interact)
 
@@ -51,11 +69,22 @@ func SetupRouter() *gin.Engine {
 			protected.GET("/user/me", handlers.GetCurrentUser)
 
 			// Economy endpoints
+			protected.GET("/wallet/challenge", handlers.GetWalletChallenge)
+			protected.POST("/wallet/link", handlers.LinkWallet)
 			protected.POST("/economy/deposit", handlers.Deposit)
+			protected.GET("/economy/ledger", handlers.GetLedger)
+			protected.GET("/economy/deposits/:tx_hash", handlers.GetDeposit)
 
 			// Relationship endpoint
 			protected.GET("/relationship/:companion_id", handlers.GetRelationship)
 		}
+
+		// Admin routes (require the admin app role)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.RequireAppRole("admin"), middleware.Idempotency())
+		{
+			admin.POST("/pricing/rates", handlers.PublishCoinRate)
+		}
 	}
 
 	// Health check
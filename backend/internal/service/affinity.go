@@ -1,5 +1,12 @@
 package service
 
+// Actions that earn Nakama XP
+const (
+	XPActionViewStory = "view_story"
+	XPActionSentMsg   = "sent_msg"
+	XPActionSendGift  = "send_gift"
+)
+
 // CalculateLevel calculates the level based on XP
 // Formula: level = floor(xp / 100) + 1
 func CalculateLevel(xp int) int {
@@ -9,8 +16,9 @@ func CalculateLevel(xp int) int {
 // GetXPForAction returns the XP reward for a specific action
 func GetXPForAction(action string) int {
 	xpRewards := map[string]int{
-		"view_story": 5,
-		"sent_msg":   1,
+		XPActionViewStory: 5,
+		XPActionSentMsg:   1,
+		XPActionSendGift:  10,
 	}
 
 	if xp, exists := xpRewards[action]; exists {
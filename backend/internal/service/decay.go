@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// Relationships scanned per query while decaying
+	decayBatchSize = 500
+
+	// RelationshipEventDecay is recorded when time away changes a relationship
+	RelationshipEventDecay = "decay"
+)
+
+// DecayConfig controls how neglected relationships cool off
+type DecayConfig struct {
+	After     time.Duration // Time since the last interaction before decay starts
+	PerDay    int           // Affinity points lost per full day after that
+	MinScore  int           // Decay never takes a score below this
+	BatchSize int
+}
+
+// DefaultDecayConfig returns the decay settings, overridable with
+// AFFINITY_DECAY_AFTER (default 48h) and AFFINITY_DECAY_PER_DAY (default 2,
+// 0 disables decay but still recomputes moods)
+func DefaultDecayConfig() DecayConfig {
+	cfg := DecayConfig{
+		After:     48 * time.Hour,
+		PerDay:    2,
+		MinScore:  0,
+		BatchSize: decayBatchSize,
+	}
+	if d, err := time.ParseDuration(os.Getenv("AFFINITY_DECAY_AFTER")); err == nil && d >= 0 {
+		cfg.After = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("AFFINITY_DECAY_PER_DAY")); err == nil && n >= 0 {
+		cfg.PerDay = n
+	}
+	return cfg
+}
+
+// idleRelationship is a relationship the user hasn't touched recently
+type idleRelationship struct {
+	userID, companionID string
+	score               int
+	mood                MoodState
+	lastInteraction     time.Time
+	decayedAt           sql.NullTime
+}
+
+// DecayRelationships applies decay to relationships idle longer than
+// cfg.After and recomputes the mood of every idle relationship, recording
+// each change as a relationship event. It returns how many changed.
+func DecayRelationships(ctx context.Context, db *sql.DB, cfg DecayConfig, now time.Time) (int, error) {
+	// Scan relationships idle long enough to decay or for time away to change their mood
+	rules := Rules()
+	idle := cfg.After
+	if t := rules.idleThreshold(); t > 0 && t < idle {
+		idle = t
+	}
+	// Relationships decayed within the last day have no decay due yet, so they
+	// only need a look while time away can still change their mood
+	scan := idleScan{
+		idleSince:     now.Add(-idle),
+		decayedSince:  now.Add(-24 * time.Hour),
+		moodIdleSince: now.Add(-rules.longestIdle()),
+	}
+
+	changed := 0
+	afterUser, afterCompanion := "", ""
+	for {
+		batch, err := idleRelationships(ctx, db, scan, afterUser, afterCompanion, cfg.BatchSize)
+		if err != nil {
+			return changed, err
+		}
+
+		for _, rel := range batch {
+			if ctx.Err() != nil {
+				return changed, ctx.Err()
+			}
+			ok, err := decayRelationship(ctx, db, cfg, rel, now)
+			if err != nil {
+				return changed, err
+			}
+			if ok {
+				changed++
+			}
+		}
+
+		if len(batch) < cfg.BatchSize {
+			return changed, nil
+		}
+		last := batch[len(batch)-1]
+		afterUser, afterCompanion = last.userID, last.companionID
+	}
+}
+
+// idleScan selects the relationships a decay pass looks at
+type idleScan struct {
+	idleSince     time.Time // Last touched before this
+	decayedSince  time.Time // Skip those decayed after this...
+	moodIdleSince time.Time // ...unless last touched after this, so time away may still change their mood
+}
+
+// idleRelationships returns a page of relationships the scan selects
+func idleRelationships(ctx context.Context, db *sql.DB, scan idleScan, afterUser, afterCompanion string, limit int) ([]idleRelationship, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT user_id, companion_id, COALESCE(affinity_score, 0), COALESCE(current_mood, 'neutral'), last_interaction_at, decayed_at
+		FROM relationships
+		WHERE last_interaction_at < $1
+		  AND (decayed_at IS NULL OR decayed_at <= $5 OR last_interaction_at > $6)
+		  AND ($2 = '' OR (user_id::text, companion_id::text) > ($2, $3))
+		ORDER BY user_id::text, companion_id::text
+		LIMIT $4
+	`, scan.idleSince, afterUser, afterCompanion, limit, scan.decayedSince, scan.moodIdleSince)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch idle relationships: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []idleRelationship
+	for rows.Next() {
+		var rel idleRelationship
+		var mood string
+		if err := rows.Scan(&rel.userID, &rel.companionID, &rel.score, &mood, &rel.lastInteraction, &rel.decayedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		rel.mood = MoodState(mood)
+		batch = append(batch, rel)
+	}
+	return batch, rows.Err()
+}
+
+// decayRelationship applies the decay due since the last pass and the mood
+// time away implies. The update is skipped if the user interacted meanwhile.
+func decayRelationship(ctx context.Context, db *sql.DB, cfg DecayConfig, rel idleRelationship, now time.Time) (bool, error) {
+	// 1. Decay whole days since decay started (or since the last pass), keeping the remainder
+	newScore := rel.score
+	decayedAt := rel.decayedAt
+	from := rel.lastInteraction.Add(cfg.After)
+	if rel.decayedAt.Valid && rel.decayedAt.Time.After(from) {
+		from = rel.decayedAt.Time
+	}
+	if days := int(now.Sub(from) / (24 * time.Hour)); days > 0 {
+		if cfg.PerDay > 0 && newScore > cfg.MinScore {
+			newScore -= days * cfg.PerDay
+			if newScore < cfg.MinScore {
+				newScore = cfg.MinScore
+			}
+		}
+		decayedAt = sql.NullTime{Time: from.Add(time.Duration(days) * 24 * time.Hour), Valid: true}
+	}
+
+	// 2. Recompute the mood
+	newMood := moodAt(newScore, rel.lastInteraction, now)
+	if newScore == rel.score && newMood == rel.mood {
+		if decayedAt != rel.decayedAt {
+			_, err := db.ExecContext(ctx, `
+				UPDATE relationships SET decayed_at = $3
+				WHERE user_id = $1 AND companion_id = $2 AND last_interaction_at = $4
+			`, rel.userID, rel.companionID, decayedAt, rel.lastInteraction)
+			if err != nil {
+				return false, fmt.Errorf("failed to update relationship: %w", err)
+			}
+		}
+		return false, nil
+	}
+
+	// 3. Save it with an event, unless the user came back in the meantime
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE relationships SET affinity_score = $3, current_mood = $4, decayed_at = $5
+		WHERE user_id = $1 AND companion_id = $2 AND last_interaction_at = $6
+	`, rel.userID, rel.companionID, newScore, string(newMood), decayedAt, rel.lastInteraction)
+	if err != nil {
+		return false, fmt.Errorf("failed to update relationship: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO relationship_events (user_id, companion_id, kind, old_score, new_score, old_mood, new_mood)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, rel.userID, rel.companionID, RelationshipEventDecay, rel.score, newScore, string(rel.mood), string(newMood))
+	if err != nil {
+		return false, fmt.Errorf("failed to record relationship event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit relationship decay: %w", err)
+	}
+	return true, nil
+}
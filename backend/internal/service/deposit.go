@@ -0,0 +1,302 @@
+package service
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/pkg/eth"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// Deposit statuses, as stored in transactions.status
+const (
+	DepositPending   = "pending"
+	DepositConfirmed = "confirmed"
+	DepositFailed    = "failed"
+	// DepositNeedsReview is a sent payment that couldn't be priced automatically
+	DepositNeedsReview = "needs_review"
+)
+
+const (
+	// Pending deposits that haven't confirmed after this long are failed
+	defaultDepositTimeout = time.Hour
+	// Pending deposits checked per worker pass
+	depositBatchSize = 100
+	// Pending deposits a user can have at once
+	maxPendingDepositsPerUser = 5
+)
+
+var (
+	// ErrDepositExists is returned when the transaction was already submitted
+	ErrDepositExists = errors.New("deposit already submitted")
+	// ErrDepositNotFound is returned for unknown deposits
+	ErrDepositNotFound = errors.New("deposit not found")
+	// ErrWalletNotLinked is returned when the user has no verified wallet to deposit from
+	ErrWalletNotLinked = errors.New("wallet not linked")
+	// ErrTooManyPendingDeposits is returned when the user already has the maximum pending
+	ErrTooManyPendingDeposits = errors.New("too many pending deposits")
+)
+
+// DepositService records ETH deposits as pending and credits Hush Coins
+// exactly once when they confirm on chain
+type DepositService struct {
+	db       *sql.DB
+	verifier *eth.Verifier
+	timeout  time.Duration
+}
+
+// NewDepositService creates a deposit service that verifies with the given verifier
+func NewDepositService(db *sql.DB, verifier *eth.Verifier) *DepositService {
+	return &DepositService{db: db, verifier: verifier, timeout: defaultDepositTimeout}
+}
+
+// Submit checks a deposit on chain and records it. Deposits that are already
+// confirmed are credited right away; ones still waiting for confirmations are
+// left to the worker. Transactions the node doesn't know and invalid ones are
+// rejected without being recorded, so a hash can only be claimed by the
+// wallet that sent it. Deposits are only accepted from the user's linked
+// wallet, and a user can only have a few pending at once.
+func (s *DepositService) Submit(ctx context.Context, userID, txHash, wallet string, claimedWei *big.Int, quote *Quote) (*domain.Deposit, error) {
+	return s.submit(ctx, userID, txHash, wallet, claimedWei, quote, "")
+}
+
+// SubmitForReview records a deposit that couldn't be priced (e.g. the pack's
+// price changed after the ETH was sent) as needing review, so the payment
+// isn't lost. It is verified like Submit but no coins are credited.
+func (s *DepositService) SubmitForReview(ctx context.Context, userID, txHash, wallet string, claimedWei *big.Int, packID, reason string) (*domain.Deposit, error) {
+	return s.submit(ctx, userID, txHash, wallet, claimedWei, &Quote{PackID: packID}, reason)
+}
+
+// submit verifies and records a deposit; with a review reason it is stored
+// as needing review instead of pending
+func (s *DepositService) submit(ctx context.Context, userID, txHash, wallet string, claimedWei *big.Int, quote *Quote, reviewReason string) (*domain.Deposit, error) {
+	if wallet == "" {
+		return nil, ErrWalletNotLinked
+	}
+
+	var pending int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM transactions WHERE user_id = $1 AND status = 'pending'
+	`, userID).Scan(&pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending deposits: %w", err)
+	}
+	if pending >= maxPendingDepositsPerUser {
+		return nil, ErrTooManyPendingDeposits
+	}
+
+	// Checks the sender is the user's wallet before anything is recorded
+	payment, err := s.verifier.Verify(ctx, txHash, wallet, claimedWei)
+	confirmed := err == nil
+	if err != nil && !errors.Is(err, eth.ErrNotConfirmed) {
+		return nil, err
+	}
+
+	confirmations := 0
+	if payment != nil {
+		confirmations = int(payment.Confirmations)
+	}
+
+	var packID, rateID, reason sql.NullString
+	if quote.PackID != "" {
+		packID = sql.NullString{String: quote.PackID, Valid: true}
+	}
+	if quote.RateID != "" {
+		rateID = sql.NullString{String: quote.RateID, Valid: true}
+	}
+	status := DepositPending
+	if reviewReason != "" {
+		status = DepositNeedsReview
+		reason = sql.NullString{String: reviewReason, Valid: true}
+	}
+
+	// Coins are fixed at submission, so later rate changes don't affect pending
+	// deposits. A failed deposit never credited anything, so it can be resubmitted.
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO transactions (user_id, tx_hash, amount_eth, amount_wei, coins_granted, status, from_address, confirmations, checked_at, pack_id, rate_id, failure_reason)
+		VALUES ($1, $2, $3, $4, $5, $10, $6, $7, NOW(), $8, $9, $11)
+		ON CONFLICT (tx_hash) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			amount_eth = EXCLUDED.amount_eth,
+			amount_wei = EXCLUDED.amount_wei,
+			coins_granted = EXCLUDED.coins_granted,
+			status = EXCLUDED.status,
+			from_address = EXCLUDED.from_address,
+			confirmations = EXCLUDED.confirmations,
+			failure_reason = EXCLUDED.failure_reason,
+			checked_at = NOW(),
+			confirmed_at = NULL,
+			pack_id = EXCLUDED.pack_id,
+			rate_id = EXCLUDED.rate_id,
+			created_at = NOW()
+		WHERE transactions.status = 'failed'
+	`, userID, txHash, eth.FormatEther(claimedWei), claimedWei.String(), quote.Coins, wallet, confirmations, packID, rateID, status, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record deposit: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrDepositExists
+	}
+
+	if confirmed && status == DepositPending {
+		if err := s.confirm(ctx, txHash, confirmations); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Get(ctx, userID, txHash)
+}
+
+// Get returns the status of one of the user's deposits
+func (s *DepositService) Get(ctx context.Context, userID, txHash string) (*domain.Deposit, error) {
+	d := &domain.Deposit{RequiredConfirmations: int(s.verifier.MinConfirmations())}
+
+	var failureReason sql.NullString
+	var confirmedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT tx_hash, status, amount_eth::text, coins_granted, confirmations, failure_reason, created_at, confirmed_at
+		FROM transactions
+		WHERE tx_hash = $1 AND user_id = $2
+	`, txHash, userID).Scan(&d.TxHash, &d.Status, &d.AmountETH, &d.Coins, &d.Confirmations, &failureReason, &d.CreatedAt, &confirmedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDepositNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deposit: %w", err)
+	}
+
+	d.FailureReason = failureReason.String
+	if confirmedAt.Valid {
+		d.ConfirmedAt = &confirmedAt.Time
+	}
+	return d, nil
+}
+
+// confirm marks a pending deposit confirmed and credits its coins. The status
+// change and the ledger credit commit together, and only a pending row can be
+// confirmed, so coins are credited exactly once.
+func (s *DepositService) confirm(ctx context.Context, txHash string, confirmations int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var coins int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE transactions
+		SET status = 'confirmed', confirmations = $2, checked_at = NOW(), confirmed_at = NOW()
+		WHERE tx_hash = $1 AND status = 'pending'
+		RETURNING user_id, coins_granted
+	`, txHash, confirmations).Scan(&userID, &coins)
+	if err == sql.ErrNoRows {
+		return nil // Already confirmed or failed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to confirm deposit: %w", err)
+	}
+
+	if _, err := Credit(ctx, tx, userID, coins, AccountDeposits, LedgerKindDeposit, txHash); err != nil {
+		return fmt.Errorf("failed to credit deposit: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// fail marks a pending deposit failed
+func (s *DepositService) fail(ctx context.Context, txHash, reason string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE transactions SET status = 'failed', failure_reason = $2, checked_at = NOW()
+		WHERE tx_hash = $1 AND status = 'pending'
+	`, txHash, reason)
+	if err != nil {
+		return fmt.Errorf("failed to fail deposit: %w", err)
+	}
+	return nil
+}
+
+// ProcessPending re-checks pending deposits, least recently checked first,
+// confirming, failing or timing them out. Node errors leave a deposit pending
+// for the next pass.
+func (s *DepositService) ProcessPending(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tx_hash, COALESCE(from_address, ''), amount_wei::text, created_at
+		FROM transactions
+		WHERE status = 'pending'
+		ORDER BY checked_at ASC NULLS FIRST
+		LIMIT $1
+	`, depositBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pending deposits: %w", err)
+	}
+	defer rows.Close()
+
+	type pending struct {
+		txHash, from string
+		wei          *big.Int
+		createdAt    time.Time
+	}
+	var deposits []pending
+	for rows.Next() {
+		var p pending
+		var wei sql.NullString
+		if err := rows.Scan(&p.txHash, &p.from, &wei, &p.createdAt); err != nil {
+			return fmt.Errorf("failed to scan deposit: %w", err)
+		}
+		if v, ok := new(big.Int).SetString(wei.String, 10); ok {
+			p.wei = v
+		}
+		deposits = append(deposits, p)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read pending deposits: %w", err)
+	}
+	rows.Close()
+
+	for _, p := range deposits {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.check(ctx, p.txHash, p.from, p.wei, p.createdAt); err != nil {
+			log.Printf("deposit %s: %v", p.txHash, err)
+		}
+	}
+	return nil
+}
+
+// check verifies one pending deposit and moves it along
+func (s *DepositService) check(ctx context.Context, txHash, from string, wei *big.Int, createdAt time.Time) error {
+	if wei == nil {
+		return s.fail(ctx, txHash, "missing amount")
+	}
+
+	payment, err := s.verifier.Verify(ctx, txHash, from, wei)
+	switch {
+	case err == nil:
+		return s.confirm(ctx, txHash, int(payment.Confirmations))
+	case errors.Is(err, eth.ErrNotConfirmed), errors.Is(err, eth.ErrNotFound):
+		if time.Since(createdAt) > s.timeout {
+			return s.fail(ctx, txHash, "timed out waiting for confirmations")
+		}
+		confirmations := 0
+		if payment != nil {
+			confirmations = int(payment.Confirmations)
+		}
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE transactions SET confirmations = $2, checked_at = NOW()
+			WHERE tx_hash = $1 AND status = 'pending'
+		`, txHash, confirmations)
+		return err
+	case errors.Is(err, eth.ErrTxFailed), errors.Is(err, eth.ErrWrongRecipient),
+		errors.Is(err, eth.ErrWrongSender), errors.Is(err, eth.ErrAmountMismatch),
+		errors.Is(err, eth.ErrInvalidHash):
+		return s.fail(ctx, txHash, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Tiers, as stored in profiles.tier
+const (
+	TierFree    = "free"
+	TierPremium = "premium"
+)
+
+// Entitlements is what a user may access: their effective tier and the
+// premium stories they bought individually
+type Entitlements struct {
+	Tier     string
+	Unlocked map[string]bool
+}
+
+// IsPremium reports whether the user has premium access
+func (e *Entitlements) IsPremium() bool {
+	return e.Tier == TierPremium
+}
+
+// CanViewStory reports whether the user may see a story's media
+func (e *Entitlements) CanViewStory(storyID string, isPremium bool) bool {
+	return !isPremium || e.IsPremium() || e.Unlocked[storyID]
+}
+
+// ResolveTier returns the user's effective tier. A subscription past its grace
+// period is expired on read, so access never outlives it even if the expiry
+// job hasn't run yet.
+func ResolveTier(ctx context.Context, db *sql.DB, userID string) (string, error) {
+	var tier string
+	var status sql.NullString
+	var expiresAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(p.tier, 'free'), s.status, s.expires_at
+		FROM profiles p
+		LEFT JOIN subscriptions s ON s.user_id = p.id
+		WHERE p.id = $1
+	`, userID).Scan(&tier, &status, &expiresAt)
+	if err != nil {
+		return TierFree, fmt.Errorf("failed to fetch tier: %w", err)
+	}
+
+	lapsed := status.String == SubscriptionActive && time.Now().After(expiresAt.Time.Add(SubscriptionGracePeriod))
+	if !lapsed {
+		return tier, nil
+	}
+
+	if _, err := expireSubscriptions(ctx, db, userID); err != nil {
+		log.Printf("Failed to expire subscription for %s: %v", userID, err)
+	}
+	return TierFree, nil
+}
+
+// LoadEntitlements resolves the user's tier and unlocked stories. Anonymous
+// callers (empty userID) are free with nothing unlocked; lookup failures fall
+// back to that too, so content is never shown by mistake.
+func LoadEntitlements(ctx context.Context, db *sql.DB, userID string) *Entitlements {
+	ent := &Entitlements{Tier: TierFree}
+	if userID == "" {
+		return ent
+	}
+
+	tier, err := ResolveTier(ctx, db, userID)
+	if err != nil {
+		log.Printf("Failed to resolve tier for %s: %v", userID, err)
+	}
+	ent.Tier = tier
+
+	if ent.Unlocked, err = UnlockedStories(ctx, db, userID); err != nil {
+		log.Printf("Failed to fetch story unlocks for %s: %v", userID, err)
+	}
+	return ent
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"anikama-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrGiftNotFound is returned for unknown or inactive gifts
+	ErrGiftNotFound = errors.New("gift not found")
+	// ErrCompanionNotFound is returned for unknown companions
+	ErrCompanionNotFound = errors.New("companion not found")
+)
+
+// GiftResult is the outcome of sending a gift
+type GiftResult struct {
+	*InteractionResult
+	GiftID  string
+	Price   int
+	Balance int // After the purchase
+}
+
+// ListGifts returns the gifts on sale. Personality preferences are left out
+// so how a companion reacts stays a surprise.
+func ListGifts(ctx context.Context, db *sql.DB) ([]domain.Gift, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, emoji, coin_price
+		FROM gifts
+		WHERE active
+		ORDER BY sort_order ASC, coin_price ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch gifts: %w", err)
+	}
+	defer rows.Close()
+
+	gifts := []domain.Gift{}
+	for rows.Next() {
+		var gift domain.Gift
+		if err := rows.Scan(&gift.ID, &gift.Name, &gift.Emoji, &gift.CoinPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan gift: %w", err)
+		}
+		gifts = append(gifts, gift)
+	}
+	return gifts, rows.Err()
+}
+
+// SendGift debits a gift's price and applies its affinity delta for the
+// companion's personality, atomically. On ErrInsufficientCoins the returned
+// result carries the price and balance.
+func SendGift(ctx context.Context, db *sql.DB, userID, companionID, giftID string) (*GiftResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &GiftResult{GiftID: giftID}
+
+	// 1. Lock the profile so concurrent gifts can't overspend
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(hush_coins, 0) FROM profiles WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&result.Balance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profile: %w", err)
+	}
+
+	// 2. Get the companion's personality
+	var personalityType sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT personality_type FROM companions WHERE id = $1
+	`, companionID).Scan(&personalityType)
+	if err == sql.ErrNoRows {
+		return nil, ErrCompanionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch companion: %w", err)
+	}
+	personality := DefaultPersonality
+	if personalityType.Valid {
+		personality = PersonalityType(personalityType.String)
+	}
+
+	// 3. Get the gift and how this personality takes it
+	var delta int
+	var toast sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT g.coin_price, COALESCE(p.delta, g.base_delta), p.toast_message
+		FROM gifts g
+		LEFT JOIN gift_preferences p ON p.gift_id = g.id AND p.personality_type = $2
+		WHERE g.id = $1 AND g.active
+	`, giftID, string(personality)).Scan(&result.Price, &delta, &toast)
+	if err == sql.ErrNoRows {
+		return nil, ErrGiftNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch gift: %w", err)
+	}
+
+	// 4. Pay for it
+	if result.Balance < result.Price {
+		return result, ErrInsufficientCoins
+	}
+	ledgerTxID, err := Debit(ctx, tx, userID, result.Price, AccountGifts, LedgerKindGift, giftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit hush coins: %w", err)
+	}
+	result.Balance -= result.Price
+
+	// 5. Apply the affinity change and XP
+	result.InteractionResult, err = ApplyAffinityDelta(ctx, tx, userID, companionID, personality, delta, GetXPForAction(XPActionSendGift))
+	if err != nil {
+		return nil, err
+	}
+	if toast.String != "" {
+		result.ToastMessage = toast.String
+	}
+
+	// 6. Record the gift
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO gifts_sent (user_id, companion_id, gift_id, coins_spent, delta, ledger_transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, companionID, giftID, result.Price, delta, ledgerTxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record gift: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit gift: %w", err)
+	}
+	return result, nil
+}
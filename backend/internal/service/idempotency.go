@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IdempotencyLease is how long a request holds its key without renewing it.
+// A running request renews it (see Renew); a key left in progress past its
+// lease (e.g. the server died mid-request) can be taken over by a retry.
+const IdempotencyLease = 60 * time.Second
+
+// ErrIdempotencyLeaseLost is returned when a request's lease on its key was
+// taken over by a retry, so it may no longer store or renew anything
+var ErrIdempotencyLeaseLost = errors.New("idempotency key lease lost")
+
+// IdempotencyRecord is a request stored under an Idempotency-Key. StatusCode
+// is 0 while the original request is still in progress.
+type IdempotencyRecord struct {
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore keeps responses to mutating requests by (user, key) so
+// retries can be replayed instead of re-executed
+type IdempotencyStore interface {
+	// Reserve claims the key for a request with the given fingerprint and
+	// returns the lease token Renew, Complete and Release must present. If the
+	// key is already held (not expired, and completed or within its lease) it
+	// returns the existing record instead; a nil record means the caller now
+	// owns the key.
+	Reserve(ctx context.Context, userID, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, string, error)
+	// Renew extends the lease of a key still in progress, or returns
+	// ErrIdempotencyLeaseLost if it was taken over
+	Renew(ctx context.Context, userID, key, lease string) error
+	// Complete stores the response for a reserved key, or returns
+	// ErrIdempotencyLeaseLost if it was taken over
+	Complete(ctx context.Context, userID, key, lease string, statusCode int, contentType string, body []byte) error
+	// Release frees a reserved key so the request can be retried, unless it
+	// was taken over
+	Release(ctx context.Context, userID, key, lease string) error
+	// DeleteExpired removes records past their TTL
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// PgIdempotencyStore stores idempotency records in the idempotency_keys table
+type PgIdempotencyStore struct {
+	db *sql.DB
+}
+
+var _ IdempotencyStore = (*PgIdempotencyStore)(nil)
+
+// NewPgIdempotencyStore creates a Postgres-backed store
+func NewPgIdempotencyStore(db *sql.DB) *PgIdempotencyStore {
+	return &PgIdempotencyStore{db: db}
+}
+
+// Reserve inserts the key, taking over an expired record or a stale lease in
+// the same statement
+func (s *PgIdempotencyStore) Reserve(ctx context.Context, userID, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, string, error) {
+	lease, err := newIdempotencyLease()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var reserved bool
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, lease_token, locked_at, expires_at)
+		VALUES ($1, $2, $3, $6, NOW(), NOW() + make_interval(secs => $4))
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = NULL,
+			body = NULL,
+			created_at = NOW(),
+			lease_token = EXCLUDED.lease_token,
+			locked_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.locked_at < NOW() - make_interval(secs => $5))
+		RETURNING true
+	`, userID, key, fingerprint, ttl.Seconds(), IdempotencyLease.Seconds(), lease).Scan(&reserved)
+	if err == nil {
+		return nil, lease, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, "", fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	// Held by an earlier request
+	record := &IdempotencyRecord{}
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	err = s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, content_type, body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`, userID, key).Scan(&record.Fingerprint, &statusCode, &contentType, &record.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch idempotency key: %w", err)
+	}
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+	return record, "", nil
+}
+
+// Renew restarts the lease if the key is still in progress under it
+func (s *PgIdempotencyStore) Renew(ctx context.Context, userID, key, lease string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET locked_at = NOW()
+		WHERE user_id = $1 AND key = $2 AND lease_token = $3 AND status_code IS NULL
+	`, userID, key, lease)
+	if err != nil {
+		return fmt.Errorf("failed to renew idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrIdempotencyLeaseLost
+	}
+	return nil
+}
+
+// Complete stores the response if the key is still held under the lease
+func (s *PgIdempotencyStore) Complete(ctx context.Context, userID, key, lease string, statusCode int, contentType string, body []byte) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = $4, content_type = $5, body = $6
+		WHERE user_id = $1 AND key = $2 AND lease_token = $3 AND status_code IS NULL
+	`, userID, key, lease, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrIdempotencyLeaseLost
+	}
+	return nil
+}
+
+// Release deletes the key if it's still in progress under the lease
+func (s *PgIdempotencyStore) Release(ctx context.Context, userID, key, lease string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND lease_token = $3 AND status_code IS NULL
+	`, userID, key, lease)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired deletes records past their TTL
+func (s *PgIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// InMemoryIdempotencyStore is a process-local IdempotencyStore. It suits tests
+// and single-instance development; records are lost on restart.
+type InMemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryIdempotencyRecord // "user|key" -> record
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+	lease     string
+	lockedAt  time.Time
+	expiresAt time.Time
+}
+
+var _ IdempotencyStore = (*InMemoryIdempotencyStore)(nil)
+
+// NewInMemoryIdempotencyStore creates an empty in-process store
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{records: make(map[string]*memoryIdempotencyRecord)}
+}
+
+// Reserve claims the key unless an unexpired record holds it, taking over a
+// stale lease
+func (m *InMemoryIdempotencyStore) Reserve(ctx context.Context, userID, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := m.records[userID+"|"+key]; ok && now.Before(existing.expiresAt) {
+		stale := existing.StatusCode == 0 && now.Sub(existing.lockedAt) > IdempotencyLease
+		if !stale {
+			record := existing.IdempotencyRecord
+			return &record, "", nil
+		}
+	}
+
+	lease, err := newIdempotencyLease()
+	if err != nil {
+		return nil, "", err
+	}
+	m.records[userID+"|"+key] = &memoryIdempotencyRecord{
+		IdempotencyRecord: IdempotencyRecord{Fingerprint: fingerprint},
+		lease:             lease,
+		lockedAt:          now,
+		expiresAt:         now.Add(ttl),
+	}
+	return nil, lease, nil
+}
+
+// held returns the record if it is still in progress under the lease.
+// m.mu must be held.
+func (m *InMemoryIdempotencyStore) held(userID, key, lease string) *memoryIdempotencyRecord {
+	record, ok := m.records[userID+"|"+key]
+	if !ok || record.lease != lease || record.StatusCode != 0 {
+		return nil
+	}
+	return record
+}
+
+// Renew restarts the lease if the key is still in progress under it
+func (m *InMemoryIdempotencyStore) Renew(ctx context.Context, userID, key, lease string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := m.held(userID, key, lease)
+	if record == nil {
+		return ErrIdempotencyLeaseLost
+	}
+	record.lockedAt = time.Now()
+	return nil
+}
+
+// Complete stores the response if the key is still held under the lease
+func (m *InMemoryIdempotencyStore) Complete(ctx context.Context, userID, key, lease string, statusCode int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := m.held(userID, key, lease)
+	if record == nil {
+		return ErrIdempotencyLeaseLost
+	}
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = append([]byte(nil), body...)
+	return nil
+}
+
+// Release deletes the key if it's still in progress under the lease
+func (m *InMemoryIdempotencyStore) Release(ctx context.Context, userID, key, lease string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.held(userID, key, lease) != nil {
+		delete(m.records, userID+"|"+key)
+	}
+	return nil
+}
+
+// DeleteExpired deletes records past their TTL
+func (m *InMemoryIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	now := time.Now()
+	for k, record := range m.records {
+		if now.After(record.expiresAt) {
+			delete(m.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// newIdempotencyLease returns a random lease token
+func newIdempotencyLease() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency lease: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryIdempotencyStoreLease(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdempotencyStore()
+
+	existing, first, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil || existing != nil || first == "" {
+		t.Fatalf("first Reserve = %+v, %q, %v; want the key", existing, first, err)
+	}
+
+	// Within the lease the key stays with the first request
+	existing, lease, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil || existing == nil || existing.StatusCode != 0 || lease != "" {
+		t.Fatalf("Reserve in progress = %+v, %q, %v; want the in-progress record", existing, lease, err)
+	}
+
+	// Renewing keeps the key past the original lease
+	store.records["user-1|key-1"].lockedAt = time.Now().Add(-IdempotencyLease + time.Second)
+	if err := store.Renew(ctx, "user-1", "key-1", first); err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+	if existing, _, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour); err != nil || existing == nil {
+		t.Fatalf("Reserve after Renew = %+v, %v; want the in-progress record", existing, err)
+	}
+
+	// Once the lease is stale a retry takes it over
+	store.records["user-1|key-1"].lockedAt = time.Now().Add(-IdempotencyLease - time.Second)
+	existing, second, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil || existing != nil || second == "" || second == first {
+		t.Fatalf("Reserve after lease = %+v, %q, %v; want the key taken over with a new lease", existing, second, err)
+	}
+}
+
+func TestInMemoryIdempotencyStoreLostLease(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdempotencyStore()
+
+	_, first, _ := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	store.records["user-1|key-1"].lockedAt = time.Now().Add(-IdempotencyLease - time.Second)
+	_, second, _ := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+
+	// The first request can no longer touch the retry's record
+	if err := store.Renew(ctx, "user-1", "key-1", first); !errors.Is(err, ErrIdempotencyLeaseLost) {
+		t.Errorf("Renew with the old lease: err = %v, want ErrIdempotencyLeaseLost", err)
+	}
+	if err := store.Complete(ctx, "user-1", "key-1", first, 200, "application/json", []byte(`{"run":1}`)); !errors.Is(err, ErrIdempotencyLeaseLost) {
+		t.Errorf("Complete with the old lease: err = %v, want ErrIdempotencyLeaseLost", err)
+	}
+	if err := store.Release(ctx, "user-1", "key-1", first); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	if err := store.Complete(ctx, "user-1", "key-1", second, 200, "application/json", []byte(`{"run":2}`)); err != nil {
+		t.Fatalf("Complete with the new lease: %v", err)
+	}
+	existing, _, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil || existing == nil || string(existing.Body) != `{"run":2}` {
+		t.Fatalf("Reserve = %+v, %v; want the retry's response", existing, err)
+	}
+}
+
+func TestInMemoryIdempotencyStoreKeepsCompletedResponses(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdempotencyStore()
+
+	_, lease, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if err := store.Complete(ctx, "user-1", "key-1", lease, 201, "application/json", []byte(`{"ok":true}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	// A completed response outlives the lease, until the TTL
+	store.records["user-1|key-1"].lockedAt = time.Now().Add(-IdempotencyLease - time.Second)
+	existing, _, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil || existing == nil || existing.StatusCode != 201 || string(existing.Body) != `{"ok":true}` {
+		t.Fatalf("Reserve = %+v, %v; want the stored response", existing, err)
+	}
+
+	// Other users' keys don't collide
+	if existing, _, err := store.Reserve(ctx, "user-2", "key-1", "fp", time.Hour); err != nil || existing != nil {
+		t.Fatalf("Reserve for another user = %+v, %v; want the key", existing, err)
+	}
+
+	store.records["user-1|key-1"].expiresAt = time.Now().Add(-time.Second)
+	if n, err := store.DeleteExpired(ctx); err != nil || n != 1 {
+		t.Fatalf("DeleteExpired = %d, %v; want 1", n, err)
+	}
+}
+
+func TestInMemoryIdempotencyStoreRelease(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdempotencyStore()
+
+	_, lease, err := store.Reserve(ctx, "user-1", "key-1", "fp", time.Hour)
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if err := store.Release(ctx, "user-1", "key-1", lease); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if existing, _, err := store.Reserve(ctx, "user-1", "key-1", "other", time.Hour); err != nil || existing != nil {
+		t.Fatalf("Reserve after Release = %+v, %v; want the key", existing, err)
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"anikama-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Account identifies one side of a ledger entry: a user's wallet or a system account
+type Account string
+
+// System accounts. Their balances go negative as coins are issued to users
+// and positive as users spend them.
+const (
+	AccountDeposits       Account = "system:deposits"        // Coins bought with ETH
+	AccountChat           Account = "system:chat"            // Coins spent on chat messages
+	AccountStories        Account = "system:stories"         // Coins spent unlocking stories
+	AccountSubscriptions  Account = "system:subscriptions"   // Coins spent on premium subscriptions
+	AccountGifts          Account = "system:gifts"           // Coins spent on gifts for companions
+	AccountOpeningBalance Account = "system:opening_balance" // Balances from before the ledger
+)
+
+// Ledger transaction kinds
+const (
+	LedgerKindDeposit      = "deposit"
+	LedgerKindChatMessage  = "chat_message"
+	LedgerKindStoryUnlock  = "story_unlock"
+	LedgerKindSubscription = "subscription"
+	LedgerKindGift         = "gift"
+	LedgerKindReversal     = "reversal"
+)
+
+var (
+	// ErrInvalidAmount is returned for zero or negative amounts
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrAlreadyReversed is returned when reversing a transaction twice
+	ErrAlreadyReversed = errors.New("ledger transaction already reversed")
+	// ErrLedgerTransactionNotFound is returned when reversing an unknown transaction
+	ErrLedgerTransactionNotFound = errors.New("ledger transaction not found")
+)
+
+// UserAccount returns the wallet account of a user
+func UserAccount(userID string) Account {
+	return Account("user:" + userID)
+}
+
+// userID returns the profile ID for user accounts, or "" for system accounts
+func (a Account) userID() string {
+	if id, ok := strings.CutPrefix(string(a), "user:"); ok {
+		return id
+	}
+	return ""
+}
+
+// Credit moves amount from a system account into the user's wallet
+func Credit(ctx context.Context, tx *sql.Tx, userID string, amount int, from Account, kind, reference string) (string, error) {
+	return Transfer(ctx, tx, from, UserAccount(userID), amount, kind, reference)
+}
+
+// Debit moves amount from the user's wallet into a system account. It fails
+// with ErrInsufficientCoins if the wallet can't cover it.
+func Debit(ctx context.Context, tx *sql.Tx, userID string, amount int, to Account, kind, reference string) (string, error) {
+	return Transfer(ctx, tx, UserAccount(userID), to, amount, kind, reference)
+}
+
+// Transfer records a two-entry ledger transaction moving amount between
+// accounts and updates cached user balances. It returns the transaction ID.
+// Callers own tx so the transfer commits together with their other writes.
+func Transfer(ctx context.Context, tx *sql.Tx, from, to Account, amount int, kind, reference string) (string, error) {
+	if amount <= 0 {
+		return "", ErrInvalidAmount
+	}
+
+	var txID string
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_transactions (kind, reference) VALUES ($1, $2) RETURNING id
+	`, kind, reference).Scan(&txID)
+	if err != nil {
+		return "", fmt.Errorf("failed to record ledger transaction: %w", err)
+	}
+
+	if err := postEntries(ctx, tx, txID, map[Account]int{from: -amount, to: amount}); err != nil {
+		return "", err
+	}
+	return txID, nil
+}
+
+// Reverse records a transaction that undoes the given one. A transaction can
+// be reversed only once; user wallets must still cover the reversal.
+func Reverse(ctx context.Context, tx *sql.Tx, transactionID, reason string) (string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT account, amount FROM ledger_entries WHERE transaction_id = $1
+	`, transactionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	amounts := make(map[Account]int)
+	for rows.Next() {
+		var account Account
+		var amount int
+		if err := rows.Scan(&account, &amount); err != nil {
+			return "", fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		amounts[account] -= amount
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read ledger entries: %w", err)
+	}
+	rows.Close()
+
+	if len(amounts) == 0 {
+		return "", ErrLedgerTransactionNotFound
+	}
+
+	// The unique constraint on reverses rejects a second reversal
+	var reversalID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_transactions (kind, reference, reverses)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (reverses) DO NOTHING
+		RETURNING id
+	`, LedgerKindReversal, reason, transactionID).Scan(&reversalID)
+	if err == sql.ErrNoRows {
+		return "", ErrAlreadyReversed
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to record reversal: %w", err)
+	}
+
+	if err := postEntries(ctx, tx, reversalID, amounts); err != nil {
+		return "", err
+	}
+	return reversalID, nil
+}
+
+// postEntries inserts the entries of one transaction and applies them to the
+// cached balances of user accounts, refusing to take a wallet below zero
+func postEntries(ctx context.Context, tx *sql.Tx, txID string, amounts map[Account]int) error {
+	sum := 0
+	for _, amount := range amounts {
+		sum += amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("ledger transaction %s is unbalanced by %d", txID, sum)
+	}
+
+	// Fixed order so concurrent transfers lock profiles consistently
+	accounts := make([]Account, 0, len(amounts))
+	for account := range amounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i] < accounts[j] })
+
+	for _, account := range accounts {
+		amount := amounts[account]
+		if amount == 0 {
+			continue
+		}
+
+		var userID sql.NullString
+		if id := account.userID(); id != "" {
+			userID = sql.NullString{String: id, Valid: true}
+
+			var balance int
+			err := tx.QueryRowContext(ctx, `
+				UPDATE profiles SET hush_coins = COALESCE(hush_coins, 0) + $1, updated_at = NOW()
+				WHERE id = $2 AND COALESCE(hush_coins, 0) + $1 >= 0
+				RETURNING hush_coins
+			`, amount, id).Scan(&balance)
+			if err == sql.ErrNoRows {
+				return ErrInsufficientCoins
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update balance: %w", err)
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO ledger_entries (transaction_id, account, user_id, amount)
+			VALUES ($1, $2, $3, $4)
+		`, txID, string(account), userID, amount)
+		if err != nil {
+			return fmt.Errorf("failed to record ledger entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Balance returns the user's balance as recorded in the ledger
+func Balance(ctx context.Context, db *sql.DB, userID string) (int, error) {
+	var balance int
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account = $1
+	`, string(UserAccount(userID))).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum ledger: %w", err)
+	}
+	return balance, nil
+}
+
+// Reconcile resets the cached profiles.hush_coins to the ledger balance and
+// returns it. The profile is locked so no transfer can interleave.
+func Reconcile(ctx context.Context, db *sql.DB, userID string) (int, error) {
+	_, balance, err := reconcile(ctx, db, userID)
+	return balance, err
+}
+
+// reconcile is Reconcile, also returning the cached balance it replaced
+func reconcile(ctx context.Context, db *sql.DB, userID string) (cached, balance int, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(hush_coins, 0) FROM profiles WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&cached)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to lock profile: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account = $1
+	`, string(UserAccount(userID))).Scan(&balance)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to sum ledger: %w", err)
+	}
+
+	if cached != balance {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE profiles SET hush_coins = $1, updated_at = NOW() WHERE id = $2
+		`, balance, userID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to reconcile balance: %w", err)
+		}
+	}
+
+	return cached, balance, tx.Commit()
+}
+
+// BalanceDrift is a user whose cached balance disagreed with the ledger
+type BalanceDrift struct {
+	UserID string
+	Cached int // profiles.hush_coins before reconciling
+	Ledger int
+}
+
+// ReconcileBalances finds users whose cached profiles.hush_coins differs from
+// their ledger balance and resets it to the ledger, returning what drifted.
+// Drift means something changed hush_coins outside the ledger.
+func ReconcileBalances(ctx context.Context, db *sql.DB) ([]BalanceDrift, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.id, COALESCE(p.hush_coins, 0), COALESCE(l.balance, 0)
+		FROM profiles p
+		LEFT JOIN (
+			SELECT account, SUM(amount) AS balance FROM ledger_entries
+			WHERE account LIKE 'user:%'
+			GROUP BY account
+		) l ON l.account = 'user:' || p.id::text
+		WHERE COALESCE(p.hush_coins, 0) <> COALESCE(l.balance, 0)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare balances: %w", err)
+	}
+	defer rows.Close()
+
+	var drifted []BalanceDrift
+	for rows.Next() {
+		var d BalanceDrift
+		if err := rows.Scan(&d.UserID, &d.Cached, &d.Ledger); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		drifted = append(drifted, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read balances: %w", err)
+	}
+	rows.Close()
+
+	// Re-check under the profile lock: a transfer may have committed since
+	var reconciled []BalanceDrift
+	for _, d := range drifted {
+		if ctx.Err() != nil {
+			return reconciled, ctx.Err()
+		}
+		cached, balance, err := reconcile(ctx, db, d.UserID)
+		if err != nil {
+			return reconciled, err
+		}
+		if cached != balance {
+			reconciled = append(reconciled, BalanceDrift{UserID: d.UserID, Cached: cached, Ledger: balance})
+		}
+	}
+	return reconciled, nil
+}
+
+// LedgerHistory returns the user's entries newest first, with the running
+// balance after each. Pass the last returned Seq as before to get the next page
+// (0 starts from the newest).
+func LedgerHistory(ctx context.Context, db *sql.DB, userID string, before int64, limit int) ([]domain.LedgerEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT seq, transaction_id, kind, reference, amount, balance_after, created_at FROM (
+			SELECT e.seq, e.transaction_id, t.kind, t.reference, e.amount, e.created_at,
+			       SUM(e.amount) OVER (ORDER BY e.seq) AS balance_after
+			FROM ledger_entries e
+			JOIN ledger_transactions t ON t.id = e.transaction_id
+			WHERE e.account = $1
+		) history
+		WHERE $2 = 0 OR seq < $2
+		ORDER BY seq DESC
+		LIMIT $3
+	`, string(UserAccount(userID)), before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.LedgerEntry{}
+	for rows.Next() {
+		var e domain.LedgerEntry
+		if err := rows.Scan(&e.Seq, &e.TransactionID, &e.Kind, &e.Reference, &e.Amount, &e.BalanceAfter, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/pkg/llm"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// Messages newer than this many are left to the chat window and not summarized yet
+	memoryKeepRecent = 20
+	// Minimum number of older, unsummarized messages before a summary pass runs
+	memoryBatchSize = 20
+	// Upper bound on messages folded into the summary in one pass
+	memoryMaxBatch = 200
+	// Upper bound on remembered facts per (user, companion)
+	memoryMaxFacts = 30
+
+	memorySummarizeTimeout = time.Minute
+)
+
+const memorySystemPrompt = `You maintain the long-term memory of %s, an anime companion character, about the user they chat with.
+Merge the existing memory with the new conversation excerpt. Respond with JSON only, in this exact shape:
+{"summary": "<at most 150 words, third person, what happened between them so far>", "facts": ["<short durable fact about the user>", ...]}
+Facts are things worth remembering long-term (names, pets, preferences, important events), e.g. "User's cat is named Mochi".
+Keep still-valid existing facts, drop ones the user contradicted, and list at most 30.`
+
+// MemoryService maintains rolling conversation summaries and extracted facts so
+// companions remember users beyond the recent chat history window
+type MemoryService struct {
+	db       *sql.DB
+	provider llm.Provider
+
+	inFlight sync.Map // "user|companion" -> struct{}, one summary pass at a time
+}
+
+// NewMemoryService creates a memory service that summarizes with provider,
+// which should not be wrapped in llm.Resilient already. Summaries get their
+// own circuit breaker without retries, so a failing summarizer can't trip the
+// breaker chat relies on; a failed pass is simply retried on a later message.
+func NewMemoryService(db *sql.DB, provider llm.Provider) *MemoryService {
+	cfg := llm.DefaultResilienceConfig()
+	cfg.Timeout = memorySummarizeTimeout
+	cfg.MaxRetries = 0
+	return &MemoryService{db: db, provider: llm.NewResilient(provider, cfg)}
+}
+
+// Get returns the stored memory, or an empty memory if nothing has been summarized yet
+func (m *MemoryService) Get(ctx context.Context, userID, companionID string) (*domain.CompanionMemory, error) {
+	mem := &domain.CompanionMemory{CompanionID: companionID, Facts: []string{}}
+
+	var summarizedUntil, updatedAt sql.NullTime
+	err := m.db.QueryRowContext(ctx, `
+		SELECT summary, facts, summarized_until, updated_at
+		FROM companion_memories
+		WHERE user_id = $1 AND companion_id = $2
+	`, userID, companionID).Scan(&mem.Summary, pq.Array(&mem.Facts), &summarizedUntil, &updatedAt)
+	if err == sql.ErrNoRows {
+		return mem, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memory: %w", err)
+	}
+
+	if summarizedUntil.Valid {
+		mem.SummarizedUntil = &summarizedUntil.Time
+	}
+	if updatedAt.Valid {
+		mem.UpdatedAt = &updatedAt.Time
+	}
+	if mem.Facts == nil {
+		mem.Facts = []string{}
+	}
+	return mem, nil
+}
+
+// Forget wipes the summary and facts. Messages sent so far are marked as
+// summarized so they are not folded back into memory on the next pass.
+func (m *MemoryService) Forget(ctx context.Context, userID, companionID string) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO companion_memories (user_id, companion_id, summary, facts, summarized_until, updated_at)
+		VALUES ($1, $2, '', '{}', NOW(), NOW())
+		ON CONFLICT (user_id, companion_id)
+		DO UPDATE SET summary = '', facts = '{}', summarized_until = NOW(), updated_at = NOW()
+	`, userID, companionID)
+	if err != nil {
+		return fmt.Errorf("failed to wipe memory: %w", err)
+	}
+	return nil
+}
+
+// SummarizeAsync runs a summary pass in the background if one isn't already
+// running for this (user, companion)
+func (m *MemoryService) SummarizeAsync(userID, companionID, companionName string) {
+	key := userID + "|" + companionID
+	if _, busy := m.inFlight.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+
+	go func() {
+		defer m.inFlight.Delete(key)
+
+		ctx, cancel := context.WithTimeout(context.Background(), memorySummarizeTimeout)
+		defer cancel()
+
+		if err := m.Summarize(ctx, userID, companionID, companionName); err != nil {
+			log.Printf("memory summary failed for user %s companion %s: %v", userID, companionID, err)
+		}
+	}()
+}
+
+// Summarize folds older, not yet summarized messages into the stored summary and
+// facts. It does nothing until enough messages have left the recent window.
+func (m *MemoryService) Summarize(ctx context.Context, userID, companionID, companionName string) error {
+	mem, err := m.Get(ctx, userID, companionID)
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if mem.SummarizedUntil != nil {
+		since = *mem.SummarizedUntil
+	}
+
+	// Everything after the summary except the most recent messages, oldest first
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT role, content, created_at FROM (
+			SELECT role, content, created_at
+			FROM messages
+			WHERE user_id = $1 AND companion_id = $2 AND created_at > $3
+			ORDER BY created_at DESC
+			OFFSET $4
+		) sub ORDER BY created_at ASC
+		LIMIT $5
+	`, userID, companionID, since, memoryKeepRecent, memoryMaxBatch)
+	if err != nil {
+		return fmt.Errorf("failed to fetch messages: %w", err)
+	}
+	defer rows.Close()
+
+	var transcript strings.Builder
+	var count int
+	var lastAt time.Time
+	for rows.Next() {
+		var role, content string
+		var createdAt time.Time
+		if err := rows.Scan(&role, &content, &createdAt); err != nil {
+			return fmt.Errorf("failed to scan message: %w", err)
+		}
+		speaker := "User"
+		if role == "assistant" {
+			speaker = companionName
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", speaker, content)
+		count++
+		lastAt = createdAt
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read messages: %w", err)
+	}
+	rows.Close()
+
+	if count < memoryBatchSize {
+		return nil
+	}
+
+	existing, _ := json.Marshal(map[string]interface{}{"summary": mem.Summary, "facts": mem.Facts})
+	reply, err := m.provider.Generate(ctx, llm.Request{
+		SystemPrompt: fmt.Sprintf(memorySystemPrompt, companionName),
+		Prompt:       fmt.Sprintf("Existing memory:\n%s\n\nNew conversation excerpt:\n%s", existing, transcript.String()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	summary, facts := parseMemoryReply(reply)
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO companion_memories (user_id, companion_id, summary, facts, summarized_until, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (user_id, companion_id)
+		DO UPDATE SET summary = EXCLUDED.summary, facts = EXCLUDED.facts,
+		              summarized_until = EXCLUDED.summarized_until, updated_at = NOW()
+		WHERE companion_memories.summarized_until IS NULL
+		   OR companion_memories.summarized_until <= EXCLUDED.summarized_until
+	`, userID, companionID, summary, pq.Array(facts), lastAt)
+	if err != nil {
+		return fmt.Errorf("failed to save memory: %w", err)
+	}
+
+	return nil
+}
+
+// parseMemoryReply extracts the summary and facts from the model's JSON reply,
+// tolerating code fences and surrounding prose. If no JSON can be found the
+// whole reply is kept as the summary.
+func parseMemoryReply(reply string) (string, []string) {
+	var parsed struct {
+		Summary string   `json:"summary"`
+		Facts   []string `json:"facts"`
+	}
+
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end <= start || json.Unmarshal([]byte(reply[start:end+1]), &parsed) != nil {
+		return strings.TrimSpace(reply), []string{}
+	}
+
+	seen := make(map[string]bool)
+	facts := []string{}
+	for _, fact := range parsed.Facts {
+		fact = strings.TrimSpace(fact)
+		key := strings.ToLower(fact)
+		if fact == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		facts = append(facts, fact)
+		if len(facts) == memoryMaxFacts {
+			break
+		}
+	}
+
+	return strings.TrimSpace(parsed.Summary), facts
+}
+
+// FormatMemory renders the memory as a section for the companion's system prompt
+func FormatMemory(mem *domain.CompanionMemory) string {
+	if mem == nil || (mem.Summary == "" && len(mem.Facts) == 0) {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("What you remember about the user from earlier conversations:\n")
+	if mem.Summary != "" {
+		b.WriteString(mem.Summary)
+		b.WriteString("\n")
+	}
+	if len(mem.Facts) > 0 {
+		b.WriteString("Facts you know about the user:\n")
+		for _, fact := range mem.Facts {
+			b.WriteString("- ")
+			b.WriteString(fact)
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
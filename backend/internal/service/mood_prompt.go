@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultMoodPromptTemplate is used for companions without a prompt_template.
+// See MoodPromptData for the fields a template can use.
+const DefaultMoodPromptTemplate = `How you feel about the user right now:
+{{if .FirstMeeting -}}
+This is the first time you are talking with the user.
+{{- else -}}
+Your relationship: {{.Tier}} (affinity {{.Score}} of 100). You last talked {{.Since}}.
+Your current mood is {{.Mood}}: {{.MoodGuidance}}
+{{- end}}
+Let this color your tone in character, but never mention scores or moods directly.`
+
+// moodGuidance describes how each mood should come across in replies
+var moodGuidance = map[MoodState]string{
+	MoodNeutral: "you are relaxed and behave as you normally would.",
+	MoodHappy:   "you are in a good mood and warmer than usual.",
+	MoodFlirty:  "you are smitten and more affectionate and teasing than usual.",
+	MoodJealous: "you missed the user while they were away and are a little jealous; let them know, then warm up if they make it up to you.",
+	MoodAnnoyed: "the user has been getting on your nerves, so you are short and prickly with them.",
+	MoodSad:     "you feel hurt by how the user has treated you, so you are distant and guarded.",
+}
+
+// moodPromptTemplates caches each companion's parsed template so it is parsed
+// once rather than on every message. Only the latest template per companion
+// is kept; editing it replaces the entry.
+var moodPromptTemplates sync.Map // companion ID -> parsedMoodPrompt
+
+// parsedMoodPrompt is a cached ParseMoodPromptTemplate result
+type parsedMoodPrompt struct {
+	text string
+	tmpl *template.Template
+	err  error
+}
+
+// MoodPromptData is what mood prompt templates can use
+type MoodPromptData struct {
+	Name         string // Companion name
+	Personality  PersonalityType
+	Mood         MoodState // relationships.current_mood
+	MoodGuidance string    // How the mood should come across
+	Tier         string    // Affinity tier, as shown in the app's affinity bar
+	Score        int       // Affinity score, -100 to 100
+	Since        string    // Time since the last interaction, e.g. "3 days ago"
+	FirstMeeting bool      // True if there's no relationship yet
+}
+
+// AffinityTier names the relationship for an affinity score, matching the
+// labels of the app's affinity bar
+func AffinityTier(score int) string {
+	switch {
+	case score > 80:
+		return "Partner"
+	case score > 50:
+		return "Close Friend"
+	case score > 20:
+		return "Friend"
+	default:
+		return "Acquaintance"
+	}
+}
+
+// LoadMoodPromptData reads the user's relationship with a companion for a
+// mood prompt. A missing relationship is a first meeting.
+func LoadMoodPromptData(ctx context.Context, db *sql.DB, userID, companionID, name string, personality PersonalityType) (*MoodPromptData, error) {
+	data := &MoodPromptData{Name: name, Personality: personality, Mood: MoodNeutral}
+
+	var score sql.NullInt64
+	var mood sql.NullString
+	var lastInteraction sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT affinity_score, current_mood, last_interaction_at
+		FROM relationships
+		WHERE user_id = $1 AND companion_id = $2
+	`, userID, companionID).Scan(&score, &mood, &lastInteraction)
+	if err == sql.ErrNoRows {
+		data.FirstMeeting = true
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch relationship: %w", err)
+	}
+
+	data.Score = int(score.Int64)
+	if mood.Valid && mood.String != "" {
+		data.Mood = MoodState(mood.String)
+	}
+	data.MoodGuidance = moodGuidance[data.Mood]
+	data.Tier = AffinityTier(data.Score)
+	data.Since = "a long time ago"
+	if lastInteraction.Valid {
+		data.Since = timeAgo(time.Since(lastInteraction.Time))
+	}
+	return data, nil
+}
+
+// ParseMoodPromptTemplate compiles a companion's prompt template, rejecting
+// fields MoodPromptData doesn't have
+func ParseMoodPromptTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("mood_prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	if err := tmpl.Execute(&strings.Builder{}, MoodPromptData{}); err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// FormatMoodPrompt renders the relationship as a section for the companion's
+// system prompt, using the companion's template text or DefaultMoodPromptTemplate
+func FormatMoodPrompt(companionID, text string, data *MoodPromptData) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultMoodPromptTemplate
+	}
+	tmpl, err := cachedMoodPromptTemplate(companionID, text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// cachedMoodPromptTemplate returns the companion's parsed template, parsing it
+// again if the text changed. Invalid templates are cached too so they aren't
+// re-parsed on every message.
+func cachedMoodPromptTemplate(companionID, text string) (*template.Template, error) {
+	if cached, ok := moodPromptTemplates.Load(companionID); ok {
+		if parsed := cached.(parsedMoodPrompt); parsed.text == text {
+			return parsed.tmpl, parsed.err
+		}
+	}
+
+	tmpl, err := ParseMoodPromptTemplate(text)
+	moodPromptTemplates.Store(companionID, parsedMoodPrompt{text: text, tmpl: tmpl, err: err})
+	return tmpl, err
+}
+
+// timeAgo describes a duration in words, e.g. "just now" or "3 days ago"
+func timeAgo(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit + " ago"
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+
+	switch {
+	case d < 5*time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 14*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	default:
+		return plural(int(d/(7*24*time.Hour)), "week")
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatMoodPromptUsesCompanionTemplate(t *testing.T) {
+	data := &MoodPromptData{Name: "Zero Two", Mood: MoodJealous, Tier: "Friend", Score: 30, Since: "3 days ago"}
+
+	got, err := FormatMoodPrompt("c1", "{{.Name}} is {{.Mood}} ({{.Tier}}, last talked {{.Since}})", data)
+	if err != nil {
+		t.Fatalf("FormatMoodPrompt: %v", err)
+	}
+	if want := "Zero Two is jealous (Friend, last talked 3 days ago)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err = FormatMoodPrompt("c2", "  ", &MoodPromptData{FirstMeeting: true})
+	if err != nil {
+		t.Fatalf("FormatMoodPrompt with default template: %v", err)
+	}
+	if !strings.Contains(got, "first time") {
+		t.Errorf("default template for a first meeting = %q", got)
+	}
+}
+
+func TestFormatMoodPromptCachesTemplatesPerCompanion(t *testing.T) {
+	const companionID = "cache-test-companion"
+
+	first, err := cachedMoodPromptTemplate(companionID, "Mood: {{.Mood}}")
+	if err != nil {
+		t.Fatalf("cachedMoodPromptTemplate: %v", err)
+	}
+	if again, _ := cachedMoodPromptTemplate(companionID, "Mood: {{.Mood}}"); again != first {
+		t.Error("template was parsed again instead of coming from the cache")
+	}
+
+	// Editing the template replaces the companion's entry
+	edited, err := cachedMoodPromptTemplate(companionID, "Feeling {{.Mood}}")
+	if err != nil {
+		t.Fatalf("cachedMoodPromptTemplate: %v", err)
+	}
+	if edited == first {
+		t.Error("edited template was served from the stale entry")
+	}
+	cached, _ := moodPromptTemplates.Load(companionID)
+	if cached.(parsedMoodPrompt).text != "Feeling {{.Mood}}" {
+		t.Errorf("cache holds %q, want only the latest template", cached.(parsedMoodPrompt).text)
+	}
+
+	// Invalid templates are cached with their error
+	for i := 0; i < 2; i++ {
+		if _, err := FormatMoodPrompt(companionID, "{{.Missing}}", &MoodPromptData{}); err == nil {
+			t.Fatal("expected a template with an unknown field to be rejected")
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"anikama-backend/internal/domain"
+	"anikama-backend/pkg/eth"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+var (
+	// ErrNoRate is returned when no exchange rate is in effect
+	ErrNoRate = errors.New("no coin rate in effect")
+	// ErrPackNotFound is returned for unknown or inactive packs
+	ErrPackNotFound = errors.New("coin pack not found")
+	// ErrPriceMismatch is returned when a pack purchase doesn't pay the pack's price
+	ErrPriceMismatch = errors.New("amount does not match the pack price")
+)
+
+var weiPerEther = big.NewInt(1e18)
+
+// A rate the client was shown still applies for this long after it's replaced,
+// so a price change between GET /economy/pricing and submitting doesn't break
+// a deposit that was already sent
+const rateQuoteGrace = time.Hour
+
+// Quote is what a deposit buys, fixed when the deposit is submitted
+type Quote struct {
+	Coins  int // Including pack bonus
+	RateID string
+	PackID string // Empty for custom amounts
+}
+
+// CurrentRate returns the rate in effect at the given time
+func CurrentRate(ctx context.Context, db *sql.DB, at time.Time) (*domain.CoinRate, error) {
+	rate := &domain.CoinRate{}
+	err := db.QueryRowContext(ctx, `
+		SELECT id, coins_per_eth, effective_from, created_at
+		FROM coin_rates
+		WHERE effective_from <= $1
+		ORDER BY effective_from DESC, created_at DESC
+		LIMIT 1
+	`, at).Scan(&rate.ID, &rate.CoinsPerETH, &rate.EffectiveFrom, &rate.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoRate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coin rate: %w", err)
+	}
+	return rate, nil
+}
+
+// PublishRate schedules a new rate; it applies to deposits submitted from effectiveFrom on
+func PublishRate(ctx context.Context, db *sql.DB, coinsPerETH int64, effectiveFrom time.Time, createdBy string) (*domain.CoinRate, error) {
+	if coinsPerETH <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	rate := &domain.CoinRate{CoinsPerETH: coinsPerETH}
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO coin_rates (coins_per_eth, effective_from, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING id, effective_from, created_at
+	`, coinsPerETH, effectiveFrom, createdBy).Scan(&rate.ID, &rate.EffectiveFrom, &rate.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish coin rate: %w", err)
+	}
+	return rate, nil
+}
+
+// ListPacks returns the active packs priced at the rate
+func ListPacks(ctx context.Context, db *sql.DB, rate *domain.CoinRate) ([]domain.CoinPack, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, coins, bonus_coins
+		FROM coin_packs
+		WHERE active
+		ORDER BY sort_order ASC, coins ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coin packs: %w", err)
+	}
+	defer rows.Close()
+
+	packs := []domain.CoinPack{}
+	for rows.Next() {
+		var pack domain.CoinPack
+		if err := rows.Scan(&pack.ID, &pack.Name, &pack.Coins, &pack.BonusCoins); err != nil {
+			return nil, fmt.Errorf("failed to scan coin pack: %w", err)
+		}
+		pricePack(&pack, rate)
+		packs = append(packs, pack)
+	}
+	return packs, rows.Err()
+}
+
+// quoteRate returns the rate with id rateID if it is current or was replaced
+// less than rateQuoteGrace ago, and the current rate otherwise
+func quoteRate(ctx context.Context, db *sql.DB, rateID string, now time.Time) (*domain.CoinRate, error) {
+	current, err := CurrentRate(ctx, db, now)
+	if err != nil || rateID == "" || rateID == current.ID {
+		return current, err
+	}
+
+	rate := &domain.CoinRate{}
+	err = db.QueryRowContext(ctx, `
+		SELECT id, coins_per_eth, effective_from, created_at
+		FROM coin_rates
+		WHERE id::text = $1 AND effective_from <= $2
+	`, rateID, now).Scan(&rate.ID, &rate.CoinsPerETH, &rate.EffectiveFrom, &rate.CreatedAt)
+	if err == sql.ErrNoRows {
+		return current, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coin rate: %w", err)
+	}
+
+	// Still in effect within the grace period if it isn't older than the rate back then
+	earlier, err := CurrentRate(ctx, db, now.Add(-rateQuoteGrace))
+	if errors.Is(err, ErrNoRate) {
+		return rate, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rate.EffectiveFrom.Before(earlier.EffectiveFrom) {
+		return current, nil
+	}
+	return rate, nil
+}
+
+// QuoteDeposit prices a deposit of wei, either as a pack purchase (wei must
+// equal the pack price) or as a custom amount. It uses the rate the client was
+// shown (rateID, optional) if it was replaced less than an hour ago, and the
+// current rate otherwise.
+func QuoteDeposit(ctx context.Context, db *sql.DB, packID, rateID string, wei *big.Int) (*Quote, error) {
+	rate, err := quoteRate(ctx, db, rateID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if packID == "" {
+		coins := CoinsForWei(wei, rate.CoinsPerETH)
+		if coins <= 0 {
+			return nil, eth.ErrInvalidEthValue
+		}
+		return &Quote{Coins: coins, RateID: rate.ID}, nil
+	}
+
+	pack := domain.CoinPack{ID: packID}
+	err = db.QueryRowContext(ctx, `
+		SELECT name, coins, bonus_coins FROM coin_packs WHERE id = $1 AND active
+	`, packID).Scan(&pack.Name, &pack.Coins, &pack.BonusCoins)
+	if err == sql.ErrNoRows {
+		return nil, ErrPackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coin pack: %w", err)
+	}
+
+	pricePack(&pack, rate)
+	if pack.PriceWei != wei.String() {
+		return nil, ErrPriceMismatch
+	}
+	return &Quote{Coins: pack.TotalCoins, RateID: rate.ID, PackID: pack.ID}, nil
+}
+
+// pricePack fills in the pack's price at the rate, rounding up to whole wei
+func pricePack(pack *domain.CoinPack, rate *domain.CoinRate) {
+	price := new(big.Int).Mul(big.NewInt(int64(pack.Coins)), weiPerEther)
+	divisor := big.NewInt(rate.CoinsPerETH)
+	price.Add(price, new(big.Int).Sub(divisor, big.NewInt(1)))
+	price.Quo(price, divisor)
+
+	pack.TotalCoins = pack.Coins + pack.BonusCoins
+	pack.PriceWei = price.String()
+	pack.PriceETH = eth.FormatEther(price)
+}
+
+// CoinsForWei converts a wei amount to Hush Coins at the rate, rounding down
+func CoinsForWei(wei *big.Int, coinsPerETH int64) int {
+	coins := new(big.Int).Mul(wei, big.NewInt(coinsPerETH))
+	coins.Quo(coins, weiPerEther)
+	if !coins.IsInt64() || coins.Int64() > math.MaxInt32 {
+		return 0
+	}
+	return int(coins.Int64())
+}
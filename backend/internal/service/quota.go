@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInsufficientCoins is returned when the daily allowance is spent and the
+// user can't pay for another message
+var ErrInsufficientCoins = errors.New("insufficient hush coins")
+
+// ChatCharge describes how one chat message was paid for
+type ChatCharge struct {
+	MessageID    string // The user message paid for
+	Tier         string
+	DailyLimit   int       // Free messages per day; -1 means unlimited
+	UsedToday    int       // Including this message
+	Day          time.Time // UTC usage day the message was counted against
+	CoinsCharged int
+	Balance      int    // Hush Coins left after the charge
+	LedgerTxID   string // Ledger transaction of the debit, if any
+}
+
+// RemainingFree returns how many free messages are left today (-1 if unlimited)
+func (c *ChatCharge) RemainingFree() int {
+	if c.DailyLimit < 0 {
+		return -1
+	}
+	if left := c.DailyLimit - c.UsedToday; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// Limited reports whether the daily allowance is used up, so this or the next
+// message costs Hush Coins
+func (c *ChatCharge) Limited() bool {
+	return c.DailyLimit >= 0 && c.UsedToday >= c.DailyLimit
+}
+
+// ChargeChatMessage counts one message against the user's daily allowance and,
+// once it is spent, debits the tier's per-message cost through the ledger.
+// tier is the user's effective tier from ResolveTier and messageID the saved
+// message, used as the debit's ledger reference. It must run in the same
+// transaction as the message insert; the profile row is locked so concurrent
+// messages can't overspend.
+func ChargeChatMessage(ctx context.Context, tx *sql.Tx, userID, tier, messageID string) (*ChatCharge, error) {
+	charge := &ChatCharge{MessageID: messageID, Tier: tier, DailyLimit: -1}
+
+	// 1. Lock the profile
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(hush_coins, 0) FROM profiles WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&charge.Balance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profile: %w", err)
+	}
+
+	// 2. Get the tier's quota (no row means unmetered)
+	var cost int
+	err = tx.QueryRowContext(ctx, `
+		SELECT daily_messages, message_cost FROM chat_quotas WHERE tier = $1
+	`, charge.Tier).Scan(&charge.DailyLimit, &cost)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to fetch chat quota: %w", err)
+	}
+
+	// 3. Count the message (days are UTC)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO chat_usage (user_id, day, messages)
+		VALUES ($1, (NOW() AT TIME ZONE 'utc')::date, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET messages = chat_usage.messages + 1
+		RETURNING messages, day
+	`, userID).Scan(&charge.UsedToday, &charge.Day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record chat usage: %w", err)
+	}
+
+	// 4. Within the allowance the message is free
+	if charge.DailyLimit < 0 || charge.UsedToday <= charge.DailyLimit || cost <= 0 {
+		return charge, nil
+	}
+
+	// 5. Otherwise debit the per-message cost
+	if charge.Balance < cost {
+		charge.CoinsCharged = cost // What it would have cost
+		return charge, ErrInsufficientCoins
+	}
+	charge.LedgerTxID, err = Debit(ctx, tx, userID, cost, AccountChat, LedgerKindChatMessage, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit hush coins: %w", err)
+	}
+	charge.CoinsCharged = cost
+	charge.Balance -= cost
+
+	return charge, nil
+}
+
+// RefundChatMessage gives back the allowance slot and any coins when no reply
+// could be generated, and deletes the unanswered message so sending it again
+// doesn't leave it in the history twice. The slot is returned to the day the
+// message was charged against, even if the reply failed after midnight UTC.
+func RefundChatMessage(ctx context.Context, db *sql.DB, userID string, charge *ChatCharge) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chat_usage SET messages = GREATEST(messages - 1, 0)
+		WHERE user_id = $1 AND day = $2
+	`, userID, charge.Day.Format("2006-01-02"))
+	if err != nil {
+		return fmt.Errorf("failed to refund chat usage: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM messages WHERE id = $1 AND user_id = $2
+	`, charge.MessageID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete unanswered message: %w", err)
+	}
+
+	if charge.LedgerTxID != "" {
+		if _, err := Reverse(ctx, tx, charge.LedgerTxID, "chat reply failed"); err != nil {
+			return fmt.Errorf("failed to refund hush coins: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"anikama-backend/pkg/embedding"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of past exchanges recalled per chat message
+	recallTopK = 3
+	// Hits below this cosine similarity are not worth mentioning
+	recallMinScore = 0.35
+
+	recallIndexTimeout = 30 * time.Second
+)
+
+// RecallEntry is one past exchange (user message + companion reply) stored for semantic recall
+type RecallEntry struct {
+	ID          string // ID of the assistant message that closed the exchange
+	UserID      string
+	CompanionID string
+	Text        string
+	Vector      []float32
+	CreatedAt   time.Time
+}
+
+// RecallHit is a past exchange relevant to the current message
+type RecallHit struct {
+	Text      string
+	Score     float64
+	CreatedAt time.Time
+}
+
+// VectorIndex stores exchange embeddings per (user, companion) and finds the nearest ones
+type VectorIndex interface {
+	Add(ctx context.Context, entry RecallEntry) error
+	// Search returns up to k entries created before the given time, most similar first
+	Search(ctx context.Context, userID, companionID string, vector []float32, before time.Time, k int) ([]RecallHit, error)
+	Forget(ctx context.Context, userID, companionID string) error
+}
+
+// RecallService embeds finished exchanges and retrieves the most relevant ones
+// so companions can bring up specific past moments
+type RecallService struct {
+	embedder embedding.Embedder
+	index    VectorIndex
+}
+
+// NewRecallService creates a recall service over the given embedder and index
+func NewRecallService(embedder embedding.Embedder, index VectorIndex) *RecallService {
+	return &RecallService{embedder: embedder, index: index}
+}
+
+// Index embeds and stores one exchange
+func (r *RecallService) Index(ctx context.Context, entry RecallEntry) error {
+	vectors, err := r.embedder.Embed(ctx, []string{entry.Text})
+	if err != nil {
+		return fmt.Errorf("failed to embed exchange: %w", err)
+	}
+	entry.Vector = vectors[0]
+	return r.index.Add(ctx, entry)
+}
+
+// IndexAsync stores the exchange in the background so the chat reply isn't delayed
+func (r *RecallService) IndexAsync(entry RecallEntry) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), recallIndexTimeout)
+		defer cancel()
+
+		if err := r.Index(ctx, entry); err != nil {
+			log.Printf("recall indexing failed for message %s: %v", entry.ID, err)
+		}
+	}()
+}
+
+// Recall returns the past exchanges most relevant to the message, ignoring
+// anything from before the given time (i.e. still in the chat window)
+func (r *RecallService) Recall(ctx context.Context, userID, companionID, message string, before time.Time) ([]RecallHit, error) {
+	vectors, err := r.embedder.Embed(ctx, []string{message})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed message: %w", err)
+	}
+
+	hits, err := r.index.Search(ctx, userID, companionID, vectors[0], before, recallTopK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search past exchanges: %w", err)
+	}
+
+	relevant := hits[:0]
+	for _, hit := range hits {
+		if hit.Score >= recallMinScore {
+			relevant = append(relevant, hit)
+		}
+	}
+	return relevant, nil
+}
+
+// Forget removes every stored exchange for the (user, companion)
+func (r *RecallService) Forget(ctx context.Context, userID, companionID string) error {
+	return r.index.Forget(ctx, userID, companionID)
+}
+
+// FormatRecall renders recalled exchanges as a section for the companion's system prompt
+func FormatRecall(hits []RecallHit) string {
+	if len(hits) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Past moments with the user that may be relevant (bring them up naturally if it fits):\n")
+	for _, hit := range hits {
+		fmt.Fprintf(&b, "[%s]\n%s\n", hit.CreatedAt.Format("Jan 2, 2006"), hit.Text)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// InMemoryIndex is a process-local VectorIndex using brute-force cosine search.
+// It suits tests and single-instance development; entries are lost on restart.
+type InMemoryIndex struct {
+	mu      sync.RWMutex
+	entries map[string][]RecallEntry // "user|companion" -> entries
+}
+
+var _ VectorIndex = (*InMemoryIndex)(nil)
+
+// NewInMemoryIndex creates an empty in-process index
+func NewInMemoryIndex() *InMemoryIndex {
+	return &InMemoryIndex{entries: make(map[string][]RecallEntry)}
+}
+
+// Add stores the entry, replacing any entry with the same ID
+func (m *InMemoryIndex) Add(ctx context.Context, entry RecallEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := entry.UserID + "|" + entry.CompanionID
+	for i, existing := range m.entries[key] {
+		if existing.ID == entry.ID {
+			m.entries[key][i] = entry
+			return nil
+		}
+	}
+	m.entries[key] = append(m.entries[key], entry)
+	return nil
+}
+
+// Search scores every entry for the (user, companion) and returns the top k
+func (m *InMemoryIndex) Search(ctx context.Context, userID, companionID string, vector []float32, before time.Time, k int) ([]RecallHit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hits []RecallHit
+	for _, entry := range m.entries[userID+"|"+companionID] {
+		if !entry.CreatedAt.Before(before) {
+			continue
+		}
+		hits = append(hits, RecallHit{
+			Text:      entry.Text,
+			Score:     embedding.Cosine(vector, entry.Vector),
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// Forget drops all entries for the (user, companion)
+func (m *InMemoryIndex) Forget(ctx context.Context, userID, companionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, userID+"|"+companionID)
+	return nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PgVectorIndex is a VectorIndex backed by the message_embeddings table (pgvector)
+type PgVectorIndex struct {
+	db *sql.DB
+}
+
+var _ VectorIndex = (*PgVectorIndex)(nil)
+
+// NewPgVectorIndex creates an index over the message_embeddings table
+func NewPgVectorIndex(db *sql.DB) *PgVectorIndex {
+	return &PgVectorIndex{db: db}
+}
+
+// Add stores the entry; re-indexing the same message is a no-op
+func (p *PgVectorIndex) Add(ctx context.Context, entry RecallEntry) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO message_embeddings (message_id, user_id, companion_id, content, embedding, created_at)
+		VALUES ($1, $2, $3, $4, $5::vector, $6)
+		ON CONFLICT (message_id) DO NOTHING
+	`, entry.ID, entry.UserID, entry.CompanionID, entry.Text, vectorLiteral(entry.Vector), entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store embedding: %w", err)
+	}
+	return nil
+}
+
+// Search uses pgvector's cosine distance operator (<=>). It is an exact scan
+// of the (user, companion)'s rows; see the message_embeddings migration.
+func (p *PgVectorIndex) Search(ctx context.Context, userID, companionID string, vector []float32, before time.Time, k int) ([]RecallHit, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT content, 1 - (embedding <=> $3::vector) AS score, created_at
+		FROM message_embeddings
+		WHERE user_id = $1 AND companion_id = $2 AND created_at < $4
+		ORDER BY embedding <=> $3::vector
+		LIMIT $5
+	`, userID, companionID, vectorLiteral(vector), before, k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []RecallHit
+	for rows.Next() {
+		var hit RecallHit
+		if err := rows.Scan(&hit.Text, &hit.Score, &hit.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// Forget deletes all stored embeddings for the (user, companion)
+func (p *PgVectorIndex) Forget(ctx context.Context, userID, companionID string) error {
+	_, err := p.db.ExecContext(ctx, `
+		DELETE FROM message_embeddings WHERE user_id = $1 AND companion_id = $2
+	`, userID, companionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+	return nil
+}
+
+// vectorLiteral formats a vector in pgvector's text format: [0.1,0.2,...]
+func vectorLiteral(vec []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range vec {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
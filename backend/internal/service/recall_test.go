@@ -0,0 +1,132 @@
+package service
+
+import (
+	"anikama-backend/pkg/embedding"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestRecall() *RecallService {
+	return NewRecallService(embedding.NewHashEmbedder(256), NewInMemoryIndex())
+}
+
+func indexExchanges(t *testing.T, r *RecallService, userID, companionID string, at time.Time, texts ...string) {
+	t.Helper()
+	for i, text := range texts {
+		err := r.Index(context.Background(), RecallEntry{
+			ID:          fmt.Sprintf("%s-%s-%d", userID, companionID, i),
+			UserID:      userID,
+			CompanionID: companionID,
+			Text:        text,
+			CreatedAt:   at.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("Index: %v", err)
+		}
+	}
+}
+
+func TestRecallOrdersBySimilarity(t *testing.T) {
+	r := newTestRecall()
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	indexExchanges(t, r, "u1", "c1", start,
+		"I adopted my cat Mochi last week",
+		"My cat Mochi knocked over my coffee",
+		"I have an exam in linear algebra tomorrow",
+		"The weather was rainy all weekend",
+	)
+
+	hits, err := r.Recall(context.Background(), "u1", "c1", "my cat Mochi is sleeping", start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(hits) < 2 {
+		t.Fatalf("got %d hits, want both cat exchanges: %+v", len(hits), hits)
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score > hits[i-1].Score {
+			t.Errorf("hits not ordered by score: %+v", hits)
+		}
+	}
+	for _, hit := range hits[:2] {
+		if hit.Text != "I adopted my cat Mochi last week" && hit.Text != "My cat Mochi knocked over my coffee" {
+			t.Errorf("top hits should be about the cat, got %q", hit.Text)
+		}
+	}
+	for _, hit := range hits {
+		if hit.Score < recallMinScore {
+			t.Errorf("hit %q scored %.2f, below recallMinScore", hit.Text, hit.Score)
+		}
+	}
+}
+
+func TestRecallSkipsExchangesStillInTheChatWindow(t *testing.T) {
+	r := newTestRecall()
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	indexExchanges(t, r, "u1", "c1", start,
+		"I adopted my cat Mochi last week",
+		"My cat Mochi knocked over my coffee",
+	)
+
+	// The second exchange (start + 1m) is still in the loaded history
+	hits, err := r.Recall(context.Background(), "u1", "c1", "my cat Mochi", start.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Text != "I adopted my cat Mochi last week" {
+		t.Errorf("hits = %+v, want only the older exchange", hits)
+	}
+}
+
+func TestRecallIsScopedToUserAndCompanion(t *testing.T) {
+	r := newTestRecall()
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	indexExchanges(t, r, "u1", "c1", start, "My cat Mochi knocked over my coffee")
+	indexExchanges(t, r, "u2", "c1", start, "My cat Mochi knocked over my coffee")
+
+	if err := r.Forget(context.Background(), "u2", "c1"); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+
+	for _, tt := range []struct {
+		userID, companionID string
+		want                int
+	}{
+		{"u1", "c1", 1},
+		{"u1", "c2", 0},
+		{"u2", "c1", 0},
+	} {
+		hits, err := r.Recall(context.Background(), tt.userID, tt.companionID, "my cat Mochi", start.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Recall: %v", err)
+		}
+		if len(hits) != tt.want {
+			t.Errorf("%s/%s: got %d hits, want %d", tt.userID, tt.companionID, len(hits), tt.want)
+		}
+	}
+}
+
+func TestRecallReturnsAtMostTopK(t *testing.T) {
+	r := newTestRecall()
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	indexExchanges(t, r, "u1", "c1", start,
+		"my cat Mochi",
+		"my cat Mochi again",
+		"my cat Mochi sleeps",
+		"my cat Mochi eats",
+		"my cat Mochi plays",
+	)
+
+	hits, err := r.Recall(context.Background(), "u1", "c1", "my cat Mochi", start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if len(hits) != recallTopK {
+		t.Errorf("got %d hits, want %d", len(hits), recallTopK)
+	}
+	if hits[0].Text != "my cat Mochi" {
+		t.Errorf("best hit = %q, want the exact match", hits[0].Text)
+	}
+}
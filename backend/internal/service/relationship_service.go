@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"time"
 )
 
@@ -11,6 +14,15 @@ const (
 	PersonalityDeredere PersonalityType = "Deredere"
 	PersonalityKuudere  PersonalityType = "Kuudere"
 	PersonalityOreSama  PersonalityType = "Ore-sama"
+
+	// DefaultPersonality is used for companions without a personality_type
+	DefaultPersonality = PersonalityDeredere
+)
+
+// Affinity score bounds, matching the relationships table constraint
+const (
+	MinAffinityScore = -100
+	MaxAffinityScore = 100
 )
 
 type ReactionType string
@@ -38,117 +50,93 @@ type InteractionResult struct {
 	Delta        int       `json:"delta"`
 	NewMood      MoodState `json:"new_mood"`
 	ToastMessage string    `json:"toast_message"`
+	XP           int       `json:"xp"`
+	Level        int       `json:"level"`
+	XPGained     int       `json:"xp_gained"`
+	LeveledUp    bool      `json:"leveled_up"`
 }
 
+// CalculateDelta returns the affinity change for a reaction by a personality
+// to a story with the given mood, per the personality rules
 func CalculateDelta(personality PersonalityType, reaction ReactionType, storyMood string) int {
-	// Angry always hurts
-	if reaction == ReactionAngry {
-		return -20
-	}
-
-	// Base delta from personality (original logic)
-	baseDelta := 0
-	switch personality {
-	case PersonalityTsundere:
-		switch reaction {
-		case ReactionLove:
-			baseDelta = -2
-		case ReactionFire:
-			baseDelta = 5
-		case ReactionLaugh:
-			baseDelta = -5
-		}
-	case PersonalityDeredere:
-		switch reaction {
-		case ReactionLove:
-			baseDelta = 10
-		case ReactionFire:
-			baseDelta = -2
-		case ReactionLaugh:
-			baseDelta = 5
-		}
-	case PersonalityKuudere:
-		switch reaction {
-		case ReactionLove:
-			baseDelta = 2
-		case ReactionFire:
-			baseDelta = 0
-		case ReactionLaugh:
-			baseDelta = 0
-		}
-	case PersonalityOreSama:
-		switch reaction {
-		case ReactionLove:
-			baseDelta = 3
-		case ReactionFire:
-			baseDelta = 5
-		case ReactionLaugh:
-			baseDelta = 5
-		}
-	}
+	return Rules().delta(personality, reaction, storyMood)
+}
 
-	// Adjust based on Story Mood
-	// If story is Sad, Laughing is bad (-10), Heart is good (+5)
-	if storyMood == "sad" {
-		if reaction == ReactionLaugh {
-			return -10
-		}
-		if reaction == ReactionLove {
-			return baseDelta + 5
-		}
-	}
+// CalculateMood returns a relationship's mood from its score and how long
+// ago the user last interacted, per the personality rules
+func CalculateMood(score int, lastInteraction time.Time) MoodState {
+	return moodAt(score, lastInteraction, time.Now())
+}
 
-	// If story is Happy, Laughing is good (+5), Heart is good (+5)
-	if storyMood == "happy" {
-		if reaction == ReactionLaugh {
-			return baseDelta + 5
-		}
-		if reaction == ReactionLove {
-			return baseDelta + 5
-		}
-	}
+// moodAt is CalculateMood as of now
+func moodAt(score int, lastInteraction, now time.Time) MoodState {
+	return Rules().mood(score, now.Sub(lastInteraction))
+}
 
-	return baseDelta
+// GenerateToastMessage returns the message shown after an interaction, per the personality rules
+func GenerateToastMessage(personality PersonalityType, mood MoodState, delta int) string {
+	return Rules().toast(personality, mood, delta)
 }
 
-func CalculateMood(score int, lastInteraction time.Time) MoodState {
-	// Sad: If score < -20
-	if score < -20 {
-		return MoodSad
+// ApplyAffinityDelta adds delta to the user's relationship with a companion,
+// clamped to the score bounds, adds xp (see GetXPForAction) and recomputes the
+// mood, level and toast. It runs in the caller's transaction so the change
+// commits with whatever caused it (a reaction, a paid gift); the row is locked
+// against concurrent updates.
+func ApplyAffinityDelta(ctx context.Context, tx *sql.Tx, userID, companionID string, personality PersonalityType, delta, xp int) (*InteractionResult, error) {
+	// 1. Get the current relationship (none yet means neutral, long ago)
+	score := 0
+	currentXP := 0
+	lastInteraction := time.Now().Add(-240 * time.Hour)
+
+	var dbScore sql.NullInt64
+	var dbLastInteraction sql.NullTime
+	err := tx.QueryRowContext(ctx, `
+		SELECT affinity_score, xp, last_interaction_at
+		FROM relationships
+		WHERE user_id = $1 AND companion_id = $2
+		FOR UPDATE
+	`, userID, companionID).Scan(&dbScore, &currentXP, &dbLastInteraction)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to fetch relationship: %w", err)
 	}
-
-	// Jealous: If score > 50 AND last_interaction > 24h ago
-	if score > 50 && time.Since(lastInteraction) > 24*time.Hour {
-		return MoodJealous
+	if dbScore.Valid {
+		score = int(dbScore.Int64)
 	}
-
-	// Flirty: If score > 80
-	if score > 80 {
-		return MoodFlirty
+	if dbLastInteraction.Valid {
+		lastInteraction = dbLastInteraction.Time
 	}
 
-	// Annoyed: If score < 0
-	if score < 0 {
-		return MoodAnnoyed
+	// 2. Calculate the new state
+	newScore := score + delta
+	if newScore > MaxAffinityScore {
+		newScore = MaxAffinityScore
+	} else if newScore < MinAffinityScore {
+		newScore = MinAffinityScore
 	}
-
-	// Happy: Default if score > 0
-	if score > 0 {
-		return MoodHappy
+	newMood := CalculateMood(newScore, lastInteraction)
+	newXP := currentXP + xp
+	newLevel := CalculateLevel(newXP)
+
+	// 3. Save it
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO relationships (user_id, companion_id, affinity_score, current_mood, xp, level, last_interaction_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (user_id, companion_id)
+		DO UPDATE SET affinity_score = $3, current_mood = $4, xp = $5, level = $6, last_interaction_at = NOW()
+	`, userID, companionID, newScore, string(newMood), newXP, newLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update relationship: %w", err)
 	}
 
-	return MoodNeutral
-}
-
-func GenerateToastMessage(personality PersonalityType, mood MoodState, delta int) string {
-	if mood == MoodSad {
-		return "You broke their heart..."
-	}
-	// Simple logic for now, can be expanded
-	if delta > 0 {
-		return "Relationship deepened!"
-	} else if delta < 0 {
-		return "They didn't like that..."
-	}
-	return "No reaction."
+	return &InteractionResult{
+		NewScore:     newScore,
+		Delta:        delta,
+		NewMood:      newMood,
+		ToastMessage: GenerateToastMessage(personality, newMood, delta),
+		XP:           newXP,
+		Level:        newLevel,
+		XPGained:     xp,
+		LeveledUp:    DidLevelUp(currentXP, newXP),
+	}, nil
 }
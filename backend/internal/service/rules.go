@@ -0,0 +1,278 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesVersion is the ruleset format this build understands
+const RulesVersion = 1
+
+//go:embed rules/personality_rules.yaml
+var defaultRulesYAML []byte
+
+// Ruleset is the data behind CalculateDelta, CalculateMood and
+// GenerateToastMessage, loaded from YAML (or JSON)
+type Ruleset struct {
+	Version       int                                         `yaml:"version"`
+	Reactions     []ReactionType                              `yaml:"reactions"`
+	Overrides     map[ReactionType]int                        `yaml:"overrides"`
+	Personalities map[PersonalityType]map[ReactionType]int    `yaml:"personalities"`
+	StoryMoods    map[string]map[ReactionType]StoryAdjustment `yaml:"story_moods"`
+	Moods         []MoodRule                                  `yaml:"moods"`
+	DefaultMood   MoodState                                   `yaml:"default_mood"`
+	Toasts        []ToastRule                                 `yaml:"toasts"`
+}
+
+// StoryAdjustment changes a reaction's delta for a story mood: Set replaces
+// it, Add is added to the personality's base delta
+type StoryAdjustment struct {
+	Set *int `yaml:"set"`
+	Add *int `yaml:"add"`
+}
+
+// MoodRule matches relationships by score (inclusive bounds) and time since
+// the last interaction
+type MoodRule struct {
+	Mood     MoodState     `yaml:"mood"`
+	MinScore *int          `yaml:"min_score"`
+	MaxScore *int          `yaml:"max_score"`
+	IdleFor  time.Duration `yaml:"idle_for"`
+}
+
+// ToastRule matches an interaction by personality, resulting mood and delta
+// (inclusive bounds); empty fields match anything
+type ToastRule struct {
+	Personality PersonalityType `yaml:"personality"`
+	Mood        MoodState       `yaml:"mood"`
+	MinDelta    *int            `yaml:"min_delta"`
+	MaxDelta    *int            `yaml:"max_delta"`
+	Message     string          `yaml:"message"`
+
+	tmpl *template.Template
+}
+
+// toastData is what toast templates can use
+type toastData struct {
+	Personality PersonalityType
+	Mood        MoodState
+	Delta       int
+}
+
+// knownMoods are the moods relationships.current_mood accepts
+var knownMoods = map[MoodState]bool{
+	MoodNeutral: true, MoodHappy: true, MoodJealous: true,
+	MoodAnnoyed: true, MoodFlirty: true, MoodSad: true,
+}
+
+var currentRules atomic.Pointer[Ruleset]
+
+func init() {
+	rules, err := ParseRules(defaultRulesYAML)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded personality rules: %v", err))
+	}
+	currentRules.Store(rules)
+}
+
+// Rules returns the ruleset in use
+func Rules() *Ruleset {
+	return currentRules.Load()
+}
+
+// SetRules replaces the ruleset in use. rules must come from ParseRules or LoadRulesFile.
+func SetRules(rules *Ruleset) {
+	currentRules.Store(rules)
+}
+
+// LoadRulesFile reads and validates a ruleset file
+func LoadRulesFile(path string) (*Ruleset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read personality rules: %w", err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules decodes and validates a ruleset. Unknown fields are rejected so
+// typos don't silently fall back to defaults.
+func ParseRules(data []byte) (*Ruleset, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	rules := &Ruleset{}
+	if err := dec.Decode(rules); err != nil {
+		return nil, fmt.Errorf("invalid personality rules: %w", err)
+	}
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("invalid personality rules: %w", err)
+	}
+	return rules, nil
+}
+
+// validate checks the ruleset is complete and consistent, and compiles toast templates
+func (r *Ruleset) validate() error {
+	var errs []error
+	if r.Version != RulesVersion {
+		errs = append(errs, fmt.Errorf("version %d is not supported (want %d)", r.Version, RulesVersion))
+	}
+
+	reactions := make(map[ReactionType]bool, len(r.Reactions))
+	for _, reaction := range r.Reactions {
+		reactions[reaction] = true
+	}
+	checkReaction := func(where string, reaction ReactionType) {
+		if !reactions[reaction] {
+			errs = append(errs, fmt.Errorf("%s: unknown reaction %q", where, reaction))
+		}
+	}
+
+	for reaction := range r.Overrides {
+		checkReaction("overrides", reaction)
+	}
+	if len(r.Personalities) == 0 {
+		errs = append(errs, errors.New("no personalities"))
+	}
+	for personality, deltas := range r.Personalities {
+		for reaction := range deltas {
+			checkReaction(fmt.Sprintf("personality %q", personality), reaction)
+		}
+	}
+	for mood, adjustments := range r.StoryMoods {
+		for reaction, adj := range adjustments {
+			where := fmt.Sprintf("story mood %q", mood)
+			checkReaction(where, reaction)
+			if (adj.Set == nil) == (adj.Add == nil) {
+				errs = append(errs, fmt.Errorf("%s: %s needs exactly one of set or add", where, reaction))
+			}
+		}
+	}
+
+	if !knownMoods[r.DefaultMood] {
+		errs = append(errs, fmt.Errorf("default_mood: unknown mood %q", r.DefaultMood))
+	}
+	for i, rule := range r.Moods {
+		if !knownMoods[rule.Mood] {
+			errs = append(errs, fmt.Errorf("moods[%d]: unknown mood %q", i, rule.Mood))
+		}
+		if rule.MinScore != nil && rule.MaxScore != nil && *rule.MinScore > *rule.MaxScore {
+			errs = append(errs, fmt.Errorf("moods[%d]: min_score is above max_score", i))
+		}
+	}
+
+	if len(r.Toasts) == 0 {
+		errs = append(errs, errors.New("no toasts"))
+	}
+	for i := range r.Toasts {
+		rule := &r.Toasts[i]
+		if rule.Mood != "" && !knownMoods[rule.Mood] {
+			errs = append(errs, fmt.Errorf("toasts[%d]: unknown mood %q", i, rule.Mood))
+		}
+		if rule.Personality != "" && r.Personalities[rule.Personality] == nil {
+			errs = append(errs, fmt.Errorf("toasts[%d]: unknown personality %q", i, rule.Personality))
+		}
+		tmpl, err := template.New(fmt.Sprintf("toast%d", i)).Option("missingkey=error").Parse(rule.Message)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("toasts[%d]: %w", i, err))
+			continue
+		}
+		rule.tmpl = tmpl
+	}
+
+	return errors.Join(errs...)
+}
+
+// delta returns the affinity change for a reaction
+func (r *Ruleset) delta(personality PersonalityType, reaction ReactionType, storyMood string) int {
+	if delta, ok := r.Overrides[reaction]; ok {
+		return delta
+	}
+
+	base := r.Personalities[personality][reaction]
+	if adj, ok := r.StoryMoods[storyMood][reaction]; ok {
+		if adj.Set != nil {
+			return *adj.Set
+		}
+		return base + *adj.Add
+	}
+	return base
+}
+
+// mood returns the first matching mood for a score
+func (r *Ruleset) mood(score int, idle time.Duration) MoodState {
+	for _, rule := range r.Moods {
+		if rule.MinScore != nil && score < *rule.MinScore {
+			continue
+		}
+		if rule.MaxScore != nil && score > *rule.MaxScore {
+			continue
+		}
+		if rule.IdleFor > 0 && idle <= rule.IdleFor {
+			continue
+		}
+		return rule.Mood
+	}
+	return r.DefaultMood
+}
+
+// idleThreshold returns the shortest time away that can change a mood, or 0
+// if moods don't depend on it
+func (r *Ruleset) idleThreshold() time.Duration {
+	var threshold time.Duration
+	for _, rule := range r.Moods {
+		if rule.IdleFor > 0 && (threshold == 0 || rule.IdleFor < threshold) {
+			threshold = rule.IdleFor
+		}
+	}
+	return threshold
+}
+
+// longestIdle returns the longest time away that can change a mood, or 0 if
+// moods don't depend on it
+func (r *Ruleset) longestIdle() time.Duration {
+	var longest time.Duration
+	for _, rule := range r.Moods {
+		if rule.IdleFor > longest {
+			longest = rule.IdleFor
+		}
+	}
+	return longest
+}
+
+// toast renders the first matching toast
+func (r *Ruleset) toast(personality PersonalityType, mood MoodState, delta int) string {
+	for _, rule := range r.Toasts {
+		if rule.Personality != "" && rule.Personality != personality {
+			continue
+		}
+		if rule.Mood != "" && rule.Mood != mood {
+			continue
+		}
+		if rule.MinDelta != nil && delta < *rule.MinDelta {
+			continue
+		}
+		if rule.MaxDelta != nil && delta > *rule.MaxDelta {
+			continue
+		}
+
+		var sb strings.Builder
+		if err := rule.tmpl.Execute(&sb, toastData{Personality: personality, Mood: mood, Delta: delta}); err != nil {
+			return rule.Message
+		}
+		return sb.String()
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"anikama-backend/pkg/llm"
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Sentiment is how a chat message comes across to the companion
+type Sentiment string
+
+const (
+	SentimentNeutral    Sentiment = "neutral"
+	SentimentKind       Sentiment = "kind"
+	SentimentRude       Sentiment = "rude"
+	SentimentFlirty     Sentiment = "flirty"
+	SentimentDismissive Sentiment = "dismissive"
+)
+
+// Reaction returns the personality rules reaction for the sentiment, so chat
+// messages go through CalculateDelta like story reactions
+func (s Sentiment) Reaction() ReactionType {
+	return ReactionType("chat_" + string(s))
+}
+
+// parseSentiment maps a label to a Sentiment
+func parseSentiment(label string) (Sentiment, bool) {
+	switch s := Sentiment(label); s {
+	case SentimentNeutral, SentimentKind, SentimentRude, SentimentFlirty, SentimentDismissive:
+		return s, true
+	}
+	return "", false
+}
+
+// SentimentClassifier labels a user's chat message
+type SentimentClassifier interface {
+	Classify(ctx context.Context, message string) (Sentiment, error)
+}
+
+// RuleClassifier labels messages from keyword lists. It needs no network, so
+// it suits offline development and is the fallback for LLMClassifier.
+type RuleClassifier struct{}
+
+var _ SentimentClassifier = RuleClassifier{}
+
+// Keywords and phrases per sentiment, checked in this order
+var sentimentKeywords = []struct {
+	sentiment Sentiment
+	words     []string
+}{
+	{SentimentRude, []string{
+		"stupid", "idiot", "dumb", "shut up", "hate you", "ugly", "loser", "annoying",
+		"pathetic", "useless", "go away", "screw you", "moron",
+	}},
+	{SentimentFlirty, []string{
+		"cute", "beautiful", "gorgeous", "kiss", "go out with", "love you",
+		"marry me", "crush on", "sexy", "babe", "darling", "😘", "😍", "❤", "💕",
+	}},
+	{SentimentDismissive, []string{
+		"whatever", "idc", "don't care", "dont care", "boring", "meh", "who cares",
+		"so what",
+	}},
+	{SentimentKind, []string{
+		"thank you", "thanks", "appreciate", "so sweet", "so kind", "proud of you", "how are you",
+		"miss you", "glad", "hope you", "take care", "you're the best", "love talking",
+	}},
+}
+
+// Messages made of just one of these read as brushing the companion off
+var dismissiveReplies = map[string]bool{"k": true, "ok": true, "okay": true, "sure": true, "fine": true, "yeah": true, "lol": true}
+
+// Classify returns the first sentiment whose keywords appear in the message
+func (RuleClassifier) Classify(ctx context.Context, message string) (Sentiment, error) {
+	text := strings.ToLower(strings.TrimSpace(message))
+	if dismissiveReplies[strings.TrimRightFunc(text, unicode.IsPunct)] {
+		return SentimentDismissive, nil
+	}
+
+	// Pad with spaces and strip punctuation so keywords match whole words
+	padded := " " + strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) && r != '\'' {
+			return ' '
+		}
+		return r
+	}, text) + " "
+
+	for _, group := range sentimentKeywords {
+		for _, word := range group.words {
+			if strings.Contains(padded, " "+word+" ") || (!isASCII(word) && strings.Contains(text, word)) {
+				return group.sentiment, nil
+			}
+		}
+	}
+	return SentimentNeutral, nil
+}
+
+// isASCII reports whether s has no multi-byte characters (emoji match anywhere)
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+const sentimentSystemPrompt = `You classify how a chat message to an anime companion character comes across.
+Answer with exactly one word: kind, rude, flirty, dismissive or neutral.`
+
+// sentimentTimeout bounds the LLM call so chat replies aren't held up
+const sentimentTimeout = 5 * time.Second
+
+// sentimentFailureThreshold is how many failed calls in a row stop the
+// classifier from calling the LLM for a while
+const sentimentFailureThreshold = 5
+
+// LLMClassifier labels messages with an LLM, falling back to keyword rules
+// when the provider fails or answers with something else
+type LLMClassifier struct {
+	provider llm.Provider
+	fallback SentimentClassifier
+}
+
+var _ SentimentClassifier = (*LLMClassifier)(nil)
+
+// NewLLMClassifier creates a classifier backed by provider, which should not
+// be wrapped in llm.Resilient already. The classifier gets its own timeout
+// and circuit breaker without retries, so failed calls fall back to the rules
+// quickly and can't trip the breaker chat relies on.
+func NewLLMClassifier(provider llm.Provider) *LLMClassifier {
+	cfg := llm.DefaultResilienceConfig()
+	cfg.Timeout = sentimentTimeout
+	cfg.MaxRetries = 0
+	cfg.FailureThreshold = sentimentFailureThreshold
+	return &LLMClassifier{provider: llm.NewResilient(provider, cfg), fallback: RuleClassifier{}}
+}
+
+// Classify asks the LLM for a one-word label
+func (c *LLMClassifier) Classify(ctx context.Context, message string) (Sentiment, error) {
+	temperature := float32(0)
+	label, err := c.provider.Generate(ctx, llm.Request{
+		SystemPrompt: sentimentSystemPrompt,
+		Prompt:       message,
+		Options:      llm.GenerationOptions{MaxTokens: 5, Temperature: &temperature},
+	})
+	if err != nil {
+		log.Printf("sentiment: LLM failed, using rules: %v", err)
+		return c.fallback.Classify(ctx, message)
+	}
+
+	word := strings.ToLower(strings.TrimFunc(strings.TrimSpace(label), unicode.IsPunct))
+	if fields := strings.Fields(word); len(fields) > 0 {
+		word = fields[0]
+	}
+	if sentiment, ok := parseSentiment(word); ok {
+		return sentiment, nil
+	}
+	return c.fallback.Classify(ctx, message)
+}
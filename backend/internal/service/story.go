@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrStoryNotFound is returned for unknown stories
+	ErrStoryNotFound = errors.New("story not found")
+	// ErrStoryNotLocked is returned when unlocking a story the user can already see
+	ErrStoryNotLocked = errors.New("story is not locked")
+)
+
+// StoryUnlock is the outcome of buying a story
+type StoryUnlock struct {
+	StoryID    string
+	Price      int
+	Balance    int // After the purchase
+	LedgerTxID string
+}
+
+// UnlockStory debits a premium story's coin price and records the user's
+// entitlement to it, atomically. Free stories and stories the user already
+// owns get ErrStoryNotLocked; callers check premium access first. On
+// ErrInsufficientCoins the returned unlock carries the price and balance.
+func UnlockStory(ctx context.Context, db *sql.DB, userID, storyID string) (*StoryUnlock, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	unlock := &StoryUnlock{StoryID: storyID}
+
+	// 1. Lock the profile so concurrent unlocks can't double-charge
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(hush_coins, 0) FROM profiles WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&unlock.Balance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profile: %w", err)
+	}
+
+	// 2. Get the story and whether it's already unlocked
+	var isPremium, owned bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(s.is_premium, false), s.coin_price,
+			EXISTS (SELECT 1 FROM story_unlocks u WHERE u.user_id = $1 AND u.story_id = s.id)
+		FROM stories s
+		WHERE s.id = $2
+	`, userID, storyID).Scan(&isPremium, &unlock.Price, &owned)
+	if err == sql.ErrNoRows {
+		return nil, ErrStoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch story: %w", err)
+	}
+
+	if !isPremium || owned {
+		return unlock, ErrStoryNotLocked
+	}
+
+	// 3. Pay for it
+	if unlock.Balance < unlock.Price {
+		return unlock, ErrInsufficientCoins
+	}
+	unlock.LedgerTxID, err = Debit(ctx, tx, userID, unlock.Price, AccountStories, LedgerKindStoryUnlock, storyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit hush coins: %w", err)
+	}
+	unlock.Balance -= unlock.Price
+
+	// 4. Record the entitlement
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO story_unlocks (user_id, story_id, coins_spent, ledger_transaction_id)
+		VALUES ($1, $2, $3, $4)
+	`, userID, storyID, unlock.Price, unlock.LedgerTxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record story unlock: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit story unlock: %w", err)
+	}
+	return unlock, nil
+}
+
+// UnlockedStories returns the IDs of the stories the user has bought
+func UnlockedStories(ctx context.Context, db *sql.DB, userID string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT story_id FROM story_unlocks WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch story unlocks: %w", err)
+	}
+	defer rows.Close()
+
+	unlocked := make(map[string]bool)
+	for rows.Next() {
+		var storyID string
+		if err := rows.Scan(&storyID); err != nil {
+			return nil, fmt.Errorf("failed to scan story unlock: %w", err)
+		}
+		unlocked[storyID] = true
+	}
+	return unlocked, rows.Err()
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"anikama-backend/internal/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SubscriptionGracePeriod is how long premium is kept after a subscription
+// expires, so the user can renew without losing it
+const SubscriptionGracePeriod = 3 * 24 * time.Hour
+
+// Subscription statuses, as stored in subscriptions.status
+const (
+	SubscriptionActive  = "active"
+	SubscriptionExpired = "expired"
+	// SubscriptionGrace is only reported, never stored: active but past expires_at
+	SubscriptionGrace = "grace"
+)
+
+// ErrPlanNotFound is returned for unknown or inactive subscription plans
+var ErrPlanNotFound = errors.New("subscription plan not found")
+
+// SubscriptionPurchase is the outcome of buying premium
+type SubscriptionPurchase struct {
+	Subscription *domain.Subscription
+	Price        int
+	Balance      int // After the purchase
+}
+
+// ListPlans returns the subscription plans on sale
+func ListPlans(ctx context.Context, db *sql.DB) ([]domain.SubscriptionPlan, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, days, coin_price
+		FROM subscription_plans
+		WHERE active
+		ORDER BY sort_order ASC, days ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []domain.SubscriptionPlan{}
+	for rows.Next() {
+		var plan domain.SubscriptionPlan
+		if err := rows.Scan(&plan.ID, &plan.Name, &plan.Days, &plan.CoinPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription plan: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// GetSubscription returns the user's subscription, or nil if they never had one
+func GetSubscription(ctx context.Context, db *sql.DB, userID string) (*domain.Subscription, error) {
+	sub := &domain.Subscription{}
+	err := db.QueryRowContext(ctx, `
+		SELECT plan_id, status, started_at, expires_at FROM subscriptions WHERE user_id = $1
+	`, userID).Scan(&sub.PlanID, &sub.Status, &sub.StartedAt, &sub.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+	describeSubscription(sub, time.Now())
+	return sub, nil
+}
+
+// PurchaseSubscription debits a plan's price and grants premium for its days,
+// atomically. Renewing an active subscription (or one in its grace period)
+// extends it from its current expiry; otherwise it starts now. On
+// ErrInsufficientCoins the returned purchase carries the price and balance.
+func PurchaseSubscription(ctx context.Context, db *sql.DB, userID, planID string) (*SubscriptionPurchase, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	purchase := &SubscriptionPurchase{}
+
+	// 1. Lock the profile so concurrent purchases can't overspend
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(hush_coins, 0) FROM profiles WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&purchase.Balance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profile: %w", err)
+	}
+
+	// 2. Get the plan
+	var days int
+	err = tx.QueryRowContext(ctx, `
+		SELECT days, coin_price FROM subscription_plans WHERE id = $1 AND active
+	`, planID).Scan(&days, &purchase.Price)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription plan: %w", err)
+	}
+
+	// 3. Pay for it
+	if purchase.Balance < purchase.Price {
+		return purchase, ErrInsufficientCoins
+	}
+	if _, err := Debit(ctx, tx, userID, purchase.Price, AccountSubscriptions, LedgerKindSubscription, planID); err != nil {
+		return nil, fmt.Errorf("failed to debit hush coins: %w", err)
+	}
+	purchase.Balance -= purchase.Price
+
+	// 4. Extend a subscription that hasn't lapsed, or start a new one
+	now := time.Now()
+	startedAt, base := now, now
+	var status string
+	var prevStarted, prevExpires time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, started_at, expires_at FROM subscriptions WHERE user_id = $1
+	`, userID).Scan(&status, &prevStarted, &prevExpires)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+	}
+	if err == nil && status == SubscriptionActive && now.Before(prevExpires.Add(SubscriptionGracePeriod)) {
+		startedAt, base = prevStarted, prevExpires
+	}
+
+	sub := &domain.Subscription{PlanID: planID, StartedAt: startedAt, ExpiresAt: base.AddDate(0, 0, days)}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO subscriptions (user_id, plan_id, status, started_at, expires_at, updated_at)
+		VALUES ($1, $2, 'active', $3, $4, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			plan_id = EXCLUDED.plan_id,
+			status = 'active',
+			started_at = EXCLUDED.started_at,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = NOW()
+	`, userID, planID, sub.StartedAt, sub.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	// 5. Upgrade the profile
+	if _, err := tx.ExecContext(ctx, `UPDATE profiles SET tier = 'premium' WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to upgrade profile: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit subscription: %w", err)
+	}
+
+	sub.Status = SubscriptionActive
+	describeSubscription(sub, now)
+	purchase.Subscription = sub
+	return purchase, nil
+}
+
+// ExpireSubscriptions marks subscriptions past their grace period expired and
+// downgrades their profiles to free. It returns how many were expired.
+func ExpireSubscriptions(ctx context.Context, db *sql.DB) (int64, error) {
+	return expireSubscriptions(ctx, db, "")
+}
+
+// expireSubscriptions expires lapsed subscriptions, only the user's if userID is set
+func expireSubscriptions(ctx context.Context, db *sql.DB, userID string) (int64, error) {
+	res, err := db.ExecContext(ctx, `
+		WITH expired AS (
+			UPDATE subscriptions SET status = 'expired', updated_at = NOW()
+			WHERE status = 'active' AND expires_at < $1 AND ($2 = '' OR user_id::text = $2)
+			RETURNING user_id
+		)
+		UPDATE profiles SET tier = 'free'
+		FROM expired
+		WHERE profiles.id = expired.user_id
+	`, time.Now().Add(-SubscriptionGracePeriod), userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire subscriptions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// describeSubscription fills in the grace deadline and reports lapsed active
+// subscriptions as in their grace period
+func describeSubscription(sub *domain.Subscription, now time.Time) {
+	sub.GraceUntil = sub.ExpiresAt.Add(SubscriptionGracePeriod)
+	if sub.Status != SubscriptionActive {
+		return
+	}
+	switch {
+	case now.After(sub.GraceUntil):
+		sub.Status = SubscriptionExpired // Not yet swept by the expiry job
+	case now.After(sub.ExpiresAt):
+		sub.Status = SubscriptionGrace
+	}
+}
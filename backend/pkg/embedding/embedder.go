@@ -0,0 +1,115 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultDimensions matches Gemini's text-embedding-004 and the pgvector column
+const DefaultDimensions = 768
+
+// Embedder turns texts into fixed-size vectors for semantic search.
+// Implementations must be safe for concurrent use.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Dimensions() int
+}
+
+// HashEmbedder is a deterministic, offline Embedder for tests and local
+// development. It hashes words and word pairs into a normalized bag-of-words
+// vector, so texts sharing vocabulary end up close together.
+type HashEmbedder struct {
+	dims int
+}
+
+var _ Embedder = (*HashEmbedder)(nil)
+
+// NewHashEmbedder creates a local embedder producing vectors of the given size
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = DefaultDimensions
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Dimensions returns the vector size
+func (h *HashEmbedder) Dimensions() int {
+	return h.dims
+}
+
+// Embed returns one unit-length vector per text
+func (h *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *HashEmbedder) embed(text string) []float32 {
+	vec := make([]float32, h.dims)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, word := range words {
+		h.add(vec, word, 1)
+		if i > 0 {
+			h.add(vec, words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	Normalize(vec)
+	return vec
+}
+
+// add hashes a feature into a bucket; a second hash bit picks the sign so
+// collisions tend to cancel out rather than pile up
+func (h *HashEmbedder) add(vec []float32, feature string, weight float32) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vec[sum%uint64(h.dims)] += weight
+}
+
+// Normalize scales vec to unit length in place
+func Normalize(vec []float32) {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+}
+
+// Cosine returns the cosine similarity of two vectors of equal length
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,212 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotFound is returned when the node doesn't know the transaction (yet)
+var ErrNotFound = errors.New("transaction not found")
+
+// RPCError is an error object returned by the node
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("eth rpc error %d: %s", e.Code, e.Message)
+}
+
+// Transaction is the subset of eth_getTransactionByHash we use
+type Transaction struct {
+	Hash        string
+	From        string   // Lowercase hex address
+	To          string   // Lowercase hex address, "" for contract creation
+	Value       *big.Int // Wei
+	BlockNumber *uint64  // nil while pending
+}
+
+// Receipt is the subset of eth_getTransactionReceipt we use
+type Receipt struct {
+	Status      uint64 // 1 success, 0 reverted
+	BlockNumber uint64
+}
+
+// Client is a minimal Ethereum JSON-RPC client. It works against any node or
+// local stand-in (anvil, hardhat, httptest) that speaks JSON-RPC over HTTP.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	nextID     atomic.Int64
+}
+
+// NewClient creates a client for the JSON-RPC endpoint at url
+func NewClient(url string) *Client {
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// NewClientFromEnv creates a client for ETH_RPC_URL
+func NewClientFromEnv() (*Client, error) {
+	url := os.Getenv("ETH_RPC_URL")
+	if url == "" {
+		return nil, fmt.Errorf("ETH_RPC_URL environment variable is not set")
+	}
+	return NewClient(url), nil
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int64         `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// call invokes method and decodes the result into out. A null result leaves
+// out untouched and returns ErrNotFound.
+func (c *Client) call(ctx context.Context, out interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	payload, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.nextID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach eth node: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("eth node returned %d", resp.StatusCode)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if len(rpcResp.Result) == 0 || string(rpcResp.Result) == "null" {
+		return ErrNotFound
+	}
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// BlockNumber returns the number of the latest block
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var hex string
+	if err := c.call(ctx, &hex, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return parseUint(hex)
+}
+
+// TransactionByHash returns the transaction, or ErrNotFound
+func (c *Client) TransactionByHash(ctx context.Context, hash string) (*Transaction, error) {
+	var raw struct {
+		Hash        string  `json:"hash"`
+		From        string  `json:"from"`
+		To          *string `json:"to"`
+		Value       string  `json:"value"`
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if err := c.call(ctx, &raw, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+
+	value, err := parseBig(raw.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction value: %w", err)
+	}
+
+	tx := &Transaction{
+		Hash:  raw.Hash,
+		From:  strings.ToLower(raw.From),
+		Value: value,
+	}
+	if raw.To != nil {
+		tx.To = strings.ToLower(*raw.To)
+	}
+	if raw.BlockNumber != nil {
+		n, err := parseUint(*raw.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block number: %w", err)
+		}
+		tx.BlockNumber = &n
+	}
+	return tx, nil
+}
+
+// TransactionReceipt returns the receipt of a mined transaction, or ErrNotFound
+func (c *Client) TransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	var raw struct {
+		Status      string `json:"status"`
+		BlockNumber string `json:"blockNumber"`
+	}
+	if err := c.call(ctx, &raw, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+
+	status, err := parseUint(raw.Status)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt status: %w", err)
+	}
+	block, err := parseUint(raw.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number: %w", err)
+	}
+	return &Receipt{Status: status, BlockNumber: block}, nil
+}
+
+// parseUint decodes a 0x-prefixed hex quantity
+func parseUint(hex string) (uint64, error) {
+	digits, ok := strings.CutPrefix(hex, "0x")
+	if !ok || digits == "" {
+		return 0, fmt.Errorf("invalid hex quantity %q", hex)
+	}
+	return strconv.ParseUint(digits, 16, 64)
+}
+
+// parseBig decodes a 0x-prefixed hex quantity of any size
+func parseBig(hex string) (*big.Int, error) {
+	digits, ok := strings.CutPrefix(hex, "0x")
+	if !ok || digits == "" {
+		return nil, fmt.Errorf("invalid hex quantity %q", hex)
+	}
+	n, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", hex)
+	}
+	return n, nil
+}
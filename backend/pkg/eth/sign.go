@@ -0,0 +1,61 @@
+package eth
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrInvalidSignature is returned for malformed or unrecoverable signatures
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Keccak256 returns the Ethereum Keccak-256 hash of data
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// HashPersonalMessage returns the EIP-191 (personal_sign) hash of msg
+func HashPersonalMessage(msg string) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(msg))
+	return Keccak256([]byte(prefix), []byte(msg))
+}
+
+// RecoverPersonalSigner returns the lowercase address that signed msg with
+// personal_sign. sig is the 65-byte r || s || v signature as hex (0x-prefixed
+// or not), with v either 0/1 or 27/28.
+func RecoverPersonalSigner(msg, sigHex string) (string, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(sigHex, "0x"))
+	if err != nil || len(sig) != 65 {
+		return "", ErrInvalidSignature
+	}
+
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return "", ErrInvalidSignature
+	}
+
+	// RecoverCompact expects [27 + recovery id] || r || s
+	compact := make([]byte, 65)
+	compact[0] = 27 + v
+	copy(compact[1:], sig[:64])
+
+	pub, _, err := ecdsa.RecoverCompact(compact, HashPersonalMessage(msg))
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	// The address is the last 20 bytes of the hash of the uncompressed key without its 0x04 prefix
+	addr := Keccak256(pub.SerializeUncompressed()[1:])[12:]
+	return "0x" + hex.EncodeToString(addr), nil
+}
@@ -0,0 +1,185 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultMinConfirmations = 12
+
+var (
+	ErrInvalidHash     = errors.New("invalid transaction hash")
+	ErrTxFailed        = errors.New("transaction reverted")
+	ErrWrongRecipient  = errors.New("transaction was not sent to the treasury")
+	ErrWrongSender     = errors.New("transaction was not sent from the user's wallet")
+	ErrAmountMismatch  = errors.New("transaction value does not match the claimed amount")
+	ErrNotConfirmed    = errors.New("transaction does not have enough confirmations yet")
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrInvalidEthValue = errors.New("invalid ETH amount")
+)
+
+var (
+	hashPattern    = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// Chain is the node API the verifier needs; *Client implements it
+type Chain interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	TransactionByHash(ctx context.Context, hash string) (*Transaction, error)
+	TransactionReceipt(ctx context.Context, hash string) (*Receipt, error)
+}
+
+var _ Chain = (*Client)(nil)
+
+// Payment is a verified transfer to the treasury
+type Payment struct {
+	Hash          string
+	From          string
+	Value         *big.Int // Wei
+	BlockNumber   uint64
+	Confirmations uint64
+}
+
+// Verifier checks that a transaction is a confirmed ETH transfer to the treasury
+type Verifier struct {
+	chain            Chain
+	treasury         string
+	minConfirmations uint64
+}
+
+// NewVerifier creates a verifier for payments to the treasury address
+func NewVerifier(chain Chain, treasury string, minConfirmations uint64) (*Verifier, error) {
+	if !IsAddress(treasury) {
+		return nil, fmt.Errorf("%w: treasury %q", ErrInvalidAddress, treasury)
+	}
+	return &Verifier{
+		chain:            chain,
+		treasury:         strings.ToLower(treasury),
+		minConfirmations: minConfirmations,
+	}, nil
+}
+
+// NewVerifierFromEnv creates a verifier from ETH_RPC_URL, ETH_TREASURY_ADDRESS
+// and ETH_MIN_CONFIRMATIONS (default 12)
+func NewVerifierFromEnv() (*Verifier, error) {
+	client, err := NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	confirmations := uint64(defaultMinConfirmations)
+	if v := os.Getenv("ETH_MIN_CONFIRMATIONS"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ETH_MIN_CONFIRMATIONS: %w", err)
+		}
+		confirmations = n
+	}
+
+	return NewVerifier(client, os.Getenv("ETH_TREASURY_ADDRESS"), confirmations)
+}
+
+// MinConfirmations returns the confirmation depth a payment needs
+func (v *Verifier) MinConfirmations() uint64 {
+	return v.minConfirmations
+}
+
+// Verify checks the transaction against the expected sender (skipped if empty)
+// and value in wei. ErrNotConfirmed is returned together with the payment so
+// callers can report progress; any other error means the payment is invalid
+// or the node couldn't be queried.
+func (v *Verifier) Verify(ctx context.Context, hash, from string, value *big.Int) (*Payment, error) {
+	if !IsTxHash(hash) {
+		return nil, ErrInvalidHash
+	}
+
+	tx, err := v.chain.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.To != v.treasury {
+		return nil, ErrWrongRecipient
+	}
+	if from != "" && tx.From != strings.ToLower(from) {
+		return nil, ErrWrongSender
+	}
+	if value != nil && tx.Value.Cmp(value) != 0 {
+		return nil, ErrAmountMismatch
+	}
+
+	payment := &Payment{Hash: strings.ToLower(hash), From: tx.From, Value: tx.Value}
+
+	// Pending transactions have no receipt yet
+	receipt, err := v.chain.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		return payment, ErrNotConfirmed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != 1 {
+		return nil, ErrTxFailed
+	}
+	payment.BlockNumber = receipt.BlockNumber
+
+	head, err := v.chain.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if head >= receipt.BlockNumber {
+		payment.Confirmations = head - receipt.BlockNumber + 1
+	}
+	if payment.Confirmations < v.minConfirmations {
+		return payment, ErrNotConfirmed
+	}
+
+	return payment, nil
+}
+
+// IsTxHash reports whether s looks like a transaction hash
+func IsTxHash(s string) bool {
+	return hashPattern.MatchString(s)
+}
+
+// IsAddress reports whether s looks like a hex address (checksum not verified)
+func IsAddress(s string) bool {
+	return addressPattern.MatchString(s)
+}
+
+var weiPerEther = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// ParseEther converts a decimal ETH amount like "0.001" to wei without
+// floating point rounding
+func ParseEther(s string) (*big.Int, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 18 || strings.HasPrefix(whole, "-") || strings.HasPrefix(whole, "+") {
+		return nil, ErrInvalidEthValue
+	}
+
+	wei, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", 18-len(frac)), 10)
+	if !ok {
+		return nil, ErrInvalidEthValue
+	}
+	return wei, nil
+}
+
+// FormatEther renders wei as a decimal ETH amount
+func FormatEther(wei *big.Int) string {
+	whole, frac := new(big.Int).QuoRem(wei, weiPerEther, new(big.Int))
+	if frac.Sign() == 0 {
+		return whole.String()
+	}
+	fracStr := strings.TrimRight(fmt.Sprintf("%018s", frac.String()), "0")
+	return whole.String() + "." + fracStr
+}
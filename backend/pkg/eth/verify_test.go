@@ -0,0 +1,191 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const (
+	testTreasury = "0x1111111111111111111111111111111111111111"
+	testWallet   = "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"
+	testOther    = "0x3333333333333333333333333333333333333333"
+)
+
+var testHash = "0x" + strings.Repeat("ab", 32)
+
+// stubChain is an in-memory Chain with one transaction
+type stubChain struct {
+	head    uint64
+	tx      *Transaction
+	receipt *Receipt
+	err     error // Returned by every call when set
+}
+
+func (s *stubChain) BlockNumber(ctx context.Context) (uint64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.head, nil
+}
+
+func (s *stubChain) TransactionByHash(ctx context.Context, hash string) (*Transaction, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.tx == nil || s.tx.Hash != hash {
+		return nil, ErrNotFound
+	}
+	return s.tx, nil
+}
+
+func (s *stubChain) TransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.receipt == nil {
+		return nil, ErrNotFound
+	}
+	return s.receipt, nil
+}
+
+// minedChain has a 1 ETH transfer from the test wallet to the treasury mined
+// in block 100, with the head at the given block
+func minedChain(head uint64) *stubChain {
+	block := uint64(100)
+	return &stubChain{
+		head: head,
+		tx: &Transaction{
+			Hash:        testHash,
+			From:        testWallet,
+			To:          testTreasury,
+			Value:       new(big.Int).Set(weiPerEther),
+			BlockNumber: &block,
+		},
+		receipt: &Receipt{Status: 1, BlockNumber: block},
+	}
+}
+
+func newTestVerifier(t *testing.T, chain Chain) *Verifier {
+	t.Helper()
+	v, err := NewVerifier(chain, testTreasury, 12)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerifyConfirmedPayment(t *testing.T) {
+	v := newTestVerifier(t, minedChain(111))
+
+	// Linked wallets may be stored checksummed
+	payment, err := v.Verify(context.Background(), testHash, "0x"+strings.ToUpper(testWallet[2:]), weiPerEther)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if payment.Confirmations != 12 || payment.BlockNumber != 100 || payment.From != testWallet {
+		t.Errorf("payment = %+v, want 12 confirmations in block 100 from the wallet", payment)
+	}
+}
+
+func TestVerifyWaitsForConfirmations(t *testing.T) {
+	v := newTestVerifier(t, minedChain(105))
+
+	payment, err := v.Verify(context.Background(), testHash, testWallet, weiPerEther)
+	if !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("err = %v, want ErrNotConfirmed", err)
+	}
+	if payment == nil || payment.Confirmations != 6 {
+		t.Errorf("payment = %+v, want progress with 6 confirmations", payment)
+	}
+}
+
+func TestVerifyPendingTransaction(t *testing.T) {
+	chain := minedChain(105)
+	chain.tx.BlockNumber = nil
+	chain.receipt = nil
+	v := newTestVerifier(t, chain)
+
+	payment, err := v.Verify(context.Background(), testHash, testWallet, weiPerEther)
+	if !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("err = %v, want ErrNotConfirmed", err)
+	}
+	if payment == nil || payment.Confirmations != 0 {
+		t.Errorf("payment = %+v, want a payment with no confirmations", payment)
+	}
+}
+
+func TestVerifyRejectsInvalidPayments(t *testing.T) {
+	tests := []struct {
+		name   string
+		hash   string
+		from   string
+		value  *big.Int
+		modify func(*stubChain)
+		want   error
+	}{
+		{name: "malformed hash", hash: "0x1234", from: testWallet, value: weiPerEther, want: ErrInvalidHash},
+		{name: "unknown transaction", hash: "0x" + strings.Repeat("cd", 32), from: testWallet, value: weiPerEther, want: ErrNotFound},
+		{name: "wrong recipient", from: testWallet, value: weiPerEther, modify: func(c *stubChain) { c.tx.To = testOther }, want: ErrWrongRecipient},
+		{name: "contract creation", from: testWallet, value: weiPerEther, modify: func(c *stubChain) { c.tx.To = "" }, want: ErrWrongRecipient},
+		{name: "wrong sender", from: testOther, value: weiPerEther, want: ErrWrongSender},
+		{name: "amount mismatch", from: testWallet, value: big.NewInt(1), want: ErrAmountMismatch},
+		{name: "reverted", from: testWallet, value: weiPerEther, modify: func(c *stubChain) { c.receipt.Status = 0 }, want: ErrTxFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := minedChain(200)
+			if tt.modify != nil {
+				tt.modify(chain)
+			}
+			hash := tt.hash
+			if hash == "" {
+				hash = testHash
+			}
+
+			payment, err := newTestVerifier(t, chain).Verify(context.Background(), hash, tt.from, tt.value)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if payment != nil {
+				t.Errorf("payment = %+v, want nil for an invalid payment", payment)
+			}
+		})
+	}
+}
+
+func TestVerifyPassesNodeErrorsThrough(t *testing.T) {
+	nodeErr := &RPCError{Code: -32000, Message: "header not found"}
+	v := newTestVerifier(t, &stubChain{err: nodeErr})
+
+	_, err := v.Verify(context.Background(), testHash, testWallet, weiPerEther)
+	if !errors.Is(err, nodeErr) {
+		t.Fatalf("err = %v, want the node error", err)
+	}
+}
+
+func TestNewVerifierRejectsBadTreasury(t *testing.T) {
+	if _, err := NewVerifier(&stubChain{}, "not-an-address", 12); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("err = %v, want ErrInvalidAddress", err)
+	}
+}
+
+func TestParseAndFormatEther(t *testing.T) {
+	for _, s := range []string{"1", "0.001", "12.5", "0.000000000000000001"} {
+		wei, err := ParseEther(s)
+		if err != nil {
+			t.Fatalf("ParseEther(%q): %v", s, err)
+		}
+		if got := FormatEther(wei); got != s {
+			t.Errorf("FormatEther(ParseEther(%q)) = %q", s, got)
+		}
+	}
+	for _, s := range []string{"-1", "0.0000000000000000001", "abc"} {
+		if _, err := ParseEther(s); !errors.Is(err, ErrInvalidEthValue) {
+			t.Errorf("ParseEther(%q) err = %v, want ErrInvalidEthValue", s, err)
+		}
+	}
+}
@@ -1,18 +1,44 @@
 package gemini
 
 import (
+	"anikama-backend/pkg/llm"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
+// System instructions need a 1.5+ model
+const defaultModel = "gemini-1.5-flash"
+
+var harmCategories = map[string]genai.HarmCategory{
+	llm.HarmHarassment:       genai.HarmCategoryHarassment,
+	llm.HarmHateSpeech:       genai.HarmCategoryHateSpeech,
+	llm.HarmSexuallyExplicit: genai.HarmCategorySexuallyExplicit,
+	llm.HarmDangerousContent: genai.HarmCategoryDangerousContent,
+}
+
+var blockThresholds = map[string]genai.HarmBlockThreshold{
+	llm.BlockNone:           genai.HarmBlockNone,
+	llm.BlockOnlyHigh:       genai.HarmBlockOnlyHigh,
+	llm.BlockMediumAndAbove: genai.HarmBlockMediumAndAbove,
+	llm.BlockLowAndAbove:    genai.HarmBlockLowAndAbove,
+}
+
+// Client is the Gemini implementation of llm.Provider
 type Client struct {
-	client *genai.GenerativeModel
+	client    *genai.Client
+	modelName string
 }
 
+var _ llm.Provider = (*Client)(nil)
+
 // NewClient creates a new Gemini AI client
 func NewClient(ctx context.Context) (*Client, error) {
 	apiKey := os.Getenv("GEMINI_API_KEY")
@@ -25,52 +51,176 @@ func NewClient(ctx context.Context) (*Client, error) {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
-	model := client.GenerativeModel("gemini-pro")
-	
+	modelName := os.Getenv("GEMINI_MODEL")
+	if modelName == "" {
+		modelName = defaultModel
+	}
+
+	return &Client{
+		client:    client,
+		modelName: modelName,
+	}, nil
+}
+
+// Close releases the underlying connection
+func (c *Client) Close() error {
+	return c.client.Close()
+}
+
+// model returns a fresh model handle configured for one request. GenerativeModel
+// holds its config in mutable fields, so it must not be shared between requests.
+func (c *Client) model(req llm.Request) *genai.GenerativeModel {
+	model := c.client.GenerativeModel(c.modelName)
+
 	// Set default generation config
 	model.SetTemperature(0.9)
 	model.SetTopK(40)
 	model.SetTopP(0.95)
 	model.SetMaxOutputTokens(1024)
 
-	return &Client{
-		client: model,
-	}, nil
+	// Apply the request's options on top
+	opts := req.Options
+	if opts.MaxTokens > 0 {
+		model.SetMaxOutputTokens(opts.MaxTokens)
+	}
+	if opts.Temperature != nil {
+		model.SetTemperature(*opts.Temperature)
+	}
+	if opts.TopP != nil {
+		model.SetTopP(*opts.TopP)
+	}
+	if opts.TopK != nil {
+		model.SetTopK(*opts.TopK)
+	}
+	if len(opts.StopSequences) > 0 {
+		model.StopSequences = append([]string(nil), opts.StopSequences...)
+	}
+	for _, setting := range opts.Safety {
+		category, ok := harmCategories[setting.Category]
+		if !ok {
+			continue
+		}
+		threshold, ok := blockThresholds[setting.Threshold]
+		if !ok {
+			continue
+		}
+		model.SafetySettings = append(model.SafetySettings, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+
+	if req.SystemPrompt != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(req.SystemPrompt)}}
+	}
+
+	return model
 }
 
-// GenerateResponse generates a response from Gemini based on system prompt and user message
-func (c *Client) GenerateResponse(ctx context.Context, systemPrompt, userMessage string, maxTokens int32) (string, error) {
-	// Combine system prompt with user message
-	fullPrompt := fmt.Sprintf("%s\n\nUser: %s\n\nAssistant:", systemPrompt, userMessage)
+// chat starts a session seeded with the request's history. Gemini expects turns
+// to alternate starting with the user, so leading model turns are dropped and
+// consecutive turns from the same author are merged.
+func (c *Client) chat(req llm.Request) *genai.ChatSession {
+	cs := c.model(req).StartChat()
 
-	// Override max tokens if specified
-	if maxTokens > 0 {
-		c.client.SetMaxOutputTokens(maxTokens)
+	for _, msg := range req.History {
+		role := "user"
+		if msg.Role == llm.RoleModel {
+			role = "model"
+		}
+		if len(cs.History) == 0 && role != "user" {
+			continue
+		}
+		if last := len(cs.History) - 1; last >= 0 && cs.History[last].Role == role {
+			cs.History[last].Parts = append(cs.History[last].Parts, genai.Text(msg.Content))
+			continue
+		}
+		cs.History = append(cs.History, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(msg.Content)}})
 	}
 
-	resp, err := c.client.GenerateContent(ctx, genai.Text(fullPrompt))
+	// The new message is sent as a user turn, so history must end on a model turn
+	if last := len(cs.History) - 1; last >= 0 && cs.History[last].Role == "user" {
+		cs.History = cs.History[:last]
+	}
+
+	return cs
+}
+
+// Generate generates a response from Gemini based on system prompt and user message
+func (c *Client) Generate(ctx context.Context, req llm.Request) (string, error) {
+	resp, err := c.chat(req).SendMessage(ctx, genai.Text(req.Prompt))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+		return "", fmt.Errorf("failed to generate content: %w", statusError(err))
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
 		return "", fmt.Errorf("no response generated")
 	}
 
 	// Extract text from response
 	text := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-	
+
 	return text, nil
 }
 
-// GenerateResponseWithLimit generates a limited response for free tier users
-func (c *Client) GenerateResponseWithLimit(ctx context.Context, systemPrompt, userMessage string) (string, error) {
-	// Free tier gets max 256 tokens (shorter responses)
-	return c.GenerateResponse(ctx, systemPrompt, userMessage, 256)
+// Stream streams a response from Gemini, calling onDelta with each text chunk
+// as it arrives. It returns the full text once the stream completes.
+func (c *Client) Stream(ctx context.Context, req llm.Request, onDelta func(string) error) (string, error) {
+	var full strings.Builder
+	iter := c.chat(req).SendMessageStream(ctx, genai.Text(req.Prompt))
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return full.String(), fmt.Errorf("failed to stream content: %w", statusError(err))
+		}
+
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			text, ok := part.(genai.Text)
+			if !ok || text == "" {
+				continue
+			}
+			full.WriteString(string(text))
+			if err := onDelta(string(text)); err != nil {
+				return full.String(), err
+			}
+		}
+	}
+
+	if full.Len() == 0 {
+		return "", fmt.Errorf("no response generated")
+	}
+
+	return full.String(), nil
+}
+
+// statusError converts Gemini API errors into llm.StatusError so the resilience
+// layer can retry rate limits and server errors
+func statusError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return &llm.StatusError{StatusCode: apiErr.Code, Message: apiErr.Message}
+	}
+	var httpErr interface{ HTTPCode() int }
+	if errors.As(err, &httpErr) && httpErr.HTTPCode() > 0 {
+		return &llm.StatusError{StatusCode: httpErr.HTTPCode(), Message: err.Error()}
+	}
+	return err
 }
 
-// GenerateResponsePremium generates a full response for premium users
-func (c *Client) GenerateResponsePremium(ctx context.Context, systemPrompt, userMessage string) (string, error) {
-	// Premium tier gets max 1024 tokens (longer responses)
-	return c.GenerateResponse(ctx, systemPrompt, userMessage, 1024)
+// CountTokens asks Gemini how many input tokens the conversation uses
+func (c *Client) CountTokens(ctx context.Context, req llm.Request) (int, error) {
+	parts := []genai.Part{genai.Text(req.SystemPrompt)}
+	for _, msg := range req.History {
+		parts = append(parts, genai.Text(msg.Content))
+	}
+	parts = append(parts, genai.Text(req.Prompt))
+
+	resp, err := c.client.GenerativeModel(c.modelName).CountTokens(ctx, parts...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return int(resp.TotalTokens), nil
 }
@@ -0,0 +1,53 @@
+package gemini
+
+import (
+	"anikama-backend/pkg/embedding"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+const defaultEmbeddingModel = "text-embedding-004"
+
+var _ embedding.Embedder = (*Client)(nil)
+
+func (c *Client) embeddingModelName() string {
+	if name := os.Getenv("GEMINI_EMBEDDING_MODEL"); name != "" {
+		return name
+	}
+	return defaultEmbeddingModel
+}
+
+// Dimensions returns the embedding size of text-embedding-004
+func (c *Client) Dimensions() int {
+	return embedding.DefaultDimensions
+}
+
+// Embed returns one embedding per text using a single batch request
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	model := c.client.EmbeddingModel(c.embeddingModelName())
+	batch := model.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
+
+	resp, err := model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed content: %w", err)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, e := range resp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
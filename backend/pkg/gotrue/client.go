@@ -0,0 +1,225 @@
+package gotrue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for the GoTrue failures callers are expected to handle
+var (
+	ErrUserExists          = errors.New("user already registered")
+	ErrWeakPassword        = errors.New("password is too weak")
+	ErrInvalidCredentials  = errors.New("invalid login credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRateLimited         = errors.New("too many requests")
+)
+
+// Error is a non-2xx response from GoTrue. It unwraps to one of the sentinel
+// errors above when the failure is recognised.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	kind       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gotrue: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// User is the subset of the GoTrue user object the backend uses
+type User struct {
+	ID           string                 `json:"id"`
+	Email        string                 `json:"email"`
+	UserMetadata map[string]interface{} `json:"user_metadata"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// Session is returned by the signup (when email confirmation is disabled) and token endpoints
+type Session struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	ExpiresAt    int64  `json:"expires_at"`
+	RefreshToken string `json:"refresh_token"`
+	User         User   `json:"user"`
+}
+
+// Client talks to the Supabase Auth (GoTrue) REST API
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a GoTrue client for the given Supabase project URL
+// (e.g. https://xyz.supabase.co or an httptest server URL)
+func NewClient(supabaseURL, apiKey string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(supabaseURL, "/") + "/auth/v1",
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewClientFromEnv creates a GoTrue client from SUPABASE_URL and SUPABASE_ANON_KEY
+func NewClientFromEnv() (*Client, error) {
+	supabaseURL := os.Getenv("SUPABASE_URL")
+	apiKey := os.Getenv("SUPABASE_ANON_KEY")
+	if supabaseURL == "" || apiKey == "" {
+		return nil, fmt.Errorf("SUPABASE_URL and SUPABASE_ANON_KEY environment variables must be set")
+	}
+	return NewClient(supabaseURL, apiKey), nil
+}
+
+// SignUp creates a new user. The returned session is nil when the project
+// requires email confirmation before the first login.
+func (c *Client) SignUp(ctx context.Context, email, password string, metadata map[string]interface{}) (*User, *Session, error) {
+	body := map[string]interface{}{
+		"email":    email,
+		"password": password,
+		"data":     metadata,
+	}
+
+	// GoTrue answers with a session when auto-confirm is on and a bare user otherwise
+	var raw json.RawMessage
+	if err := c.post(ctx, "/signup", body, &raw); err != nil {
+		return nil, nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode signup response: %w", err)
+	}
+	if session.AccessToken != "" {
+		return &session.User, &session, nil
+	}
+
+	var user User
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode signup response: %w", err)
+	}
+	return &user, nil, nil
+}
+
+// SignInWithPassword exchanges email and password for a session
+func (c *Client) SignInWithPassword(ctx context.Context, email, password string) (*Session, error) {
+	body := map[string]string{
+		"email":    email,
+		"password": password,
+	}
+
+	var session Session
+	if err := c.post(ctx, "/token?grant_type=password", body, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RefreshSession exchanges a refresh token for a new session
+func (c *Client) RefreshSession(ctx context.Context, refreshToken string) (*Session, error) {
+	body := map[string]string{
+		"refresh_token": refreshToken,
+	}
+
+	var session Session
+	if err := c.post(ctx, "/token?grant_type=refresh_token", body, &session); err != nil {
+		var gtErr *Error
+		if errors.As(err, &gtErr) && errors.Is(gtErr.kind, ErrInvalidCredentials) {
+			gtErr.kind = ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", c.apiKey)
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach auth server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read auth response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return parseError(resp.StatusCode, respBody)
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode auth response: %w", err)
+	}
+	return nil
+}
+
+// parseError understands both GoTrue error shapes:
+// {"code":422,"error_code":"user_already_exists","msg":"..."} and
+// {"error":"invalid_grant","error_description":"..."}
+func parseError(status int, body []byte) error {
+	var payload struct {
+		ErrorCode        string `json:"error_code"`
+		Msg              string `json:"msg"`
+		Message          string `json:"message"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &payload)
+
+	e := &Error{StatusCode: status, Code: payload.ErrorCode}
+	if e.Code == "" {
+		e.Code = payload.Error
+	}
+	for _, msg := range []string{payload.Msg, payload.Message, payload.ErrorDescription} {
+		if msg != "" {
+			e.Message = msg
+			break
+		}
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+
+	lowerMsg := strings.ToLower(e.Message)
+	switch {
+	case e.Code == "user_already_exists" || e.Code == "email_exists" || strings.Contains(lowerMsg, "already registered"):
+		e.kind = ErrUserExists
+	case e.Code == "weak_password" || strings.Contains(lowerMsg, "password should be"):
+		e.kind = ErrWeakPassword
+	case e.Code == "refresh_token_not_found" || e.Code == "refresh_token_already_used":
+		e.kind = ErrInvalidRefreshToken
+	case e.Code == "invalid_credentials" || e.Code == "invalid_grant":
+		e.kind = ErrInvalidCredentials
+	case status == http.StatusTooManyRequests || e.Code == "over_request_rate_limit":
+		e.kind = ErrRateLimited
+	}
+
+	return e
+}
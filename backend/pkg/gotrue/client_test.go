@@ -0,0 +1,165 @@
+package gotrue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient points a client at handler, as if it were the project's /auth/v1
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL+"/", "anon-key")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+func TestSignUpReturnsSession(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/auth/v1/signup" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("apikey"); got != "anon-key" {
+			t.Errorf("apikey = %q, want anon-key", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer anon-key" {
+			t.Errorf("Authorization = %q, want Bearer anon-key", got)
+		}
+
+		var body struct {
+			Email    string                 `json:"email"`
+			Password string                 `json:"password"`
+			Data     map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if body.Email != "a@example.com" || body.Password != "hunter22" || body.Data["username"] != "aki" {
+			t.Errorf("unexpected body %+v", body)
+		}
+
+		writeJSON(w, http.StatusOK, `{
+			"access_token": "access",
+			"token_type": "bearer",
+			"expires_in": 3600,
+			"refresh_token": "refresh",
+			"user": {"id": "user-1", "email": "a@example.com"}
+		}`)
+	})
+
+	user, session, err := c.SignUp(context.Background(), "a@example.com", "hunter22", map[string]interface{}{"username": "aki"})
+	if err != nil {
+		t.Fatalf("SignUp: %v", err)
+	}
+	if session == nil || session.AccessToken != "access" || session.RefreshToken != "refresh" {
+		t.Fatalf("session = %+v, want access/refresh tokens", session)
+	}
+	if user.ID != "user-1" || user.Email != "a@example.com" {
+		t.Errorf("user = %+v", user)
+	}
+}
+
+func TestSignUpRequiringConfirmation(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"id": "user-2", "email": "b@example.com"}`)
+	})
+
+	user, session, err := c.SignUp(context.Background(), "b@example.com", "hunter22", nil)
+	if err != nil {
+		t.Fatalf("SignUp: %v", err)
+	}
+	if session != nil {
+		t.Errorf("session = %+v, want nil until the email is confirmed", session)
+	}
+	if user.ID != "user-2" {
+		t.Errorf("user.ID = %q, want user-2", user.ID)
+	}
+}
+
+func TestSignInWithPassword(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/v1/token" || r.URL.Query().Get("grant_type") != "password" {
+			t.Errorf("unexpected request %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		writeJSON(w, http.StatusOK, `{"access_token": "access", "refresh_token": "refresh", "user": {"id": "user-1"}}`)
+	})
+
+	session, err := c.SignInWithPassword(context.Background(), "a@example.com", "hunter22")
+	if err != nil {
+		t.Fatalf("SignInWithPassword: %v", err)
+	}
+	if session.AccessToken != "access" || session.User.ID != "user-1" {
+		t.Errorf("session = %+v", session)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"user exists", 422, `{"code":422,"error_code":"user_already_exists","msg":"User already registered"}`, ErrUserExists},
+		{"legacy user exists", 400, `{"msg":"User already registered"}`, ErrUserExists},
+		{"weak password", 422, `{"error_code":"weak_password","msg":"Password should be at least 6 characters"}`, ErrWeakPassword},
+		{"invalid credentials", 400, `{"error_code":"invalid_credentials","msg":"Invalid login credentials"}`, ErrInvalidCredentials},
+		{"oauth invalid grant", 400, `{"error":"invalid_grant","error_description":"Invalid login credentials"}`, ErrInvalidCredentials},
+		{"rate limited", 429, `{"msg":"Too many requests"}`, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			})
+
+			_, err := c.SignInWithPassword(context.Background(), "a@example.com", "hunter22")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			var gtErr *Error
+			if !errors.As(err, &gtErr) || gtErr.StatusCode != tt.status {
+				t.Errorf("err = %#v, want *Error with status %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestErrorWithoutBody(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := c.SignInWithPassword(context.Background(), "a@example.com", "hunter22")
+	var gtErr *Error
+	if !errors.As(err, &gtErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if gtErr.Message != http.StatusText(http.StatusBadGateway) || errors.Unwrap(gtErr) != nil {
+		t.Errorf("err = %#v, want an unrecognised error with the status text", gtErr)
+	}
+}
+
+func TestRefreshSessionInvalidGrant(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("grant_type") != "refresh_token" {
+			t.Errorf("grant_type = %q, want refresh_token", r.URL.Query().Get("grant_type"))
+		}
+		writeJSON(w, http.StatusBadRequest, `{"error":"invalid_grant","error_description":"Invalid Refresh Token: Refresh Token Not Found"}`)
+	})
+
+	_, err := c.RefreshSession(context.Background(), "stale")
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Fake is a deterministic, offline Provider for tests and local development.
+// It returns its scripted replies in order (cycling when exhausted) or, with no
+// script, echoes the user's message.
+type Fake struct {
+	Replies []string
+
+	mu       sync.Mutex
+	calls    int
+	requests []Request
+}
+
+// NewFake creates a Fake that answers with the given replies in order
+func NewFake(replies ...string) *Fake {
+	return &Fake{Replies: replies}
+}
+
+// Requests returns a copy of every request the fake has received
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+func (f *Fake) next(req Request) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+	call := f.calls
+	f.calls++
+
+	if len(f.Replies) > 0 {
+		return f.Replies[call%len(f.Replies)]
+	}
+
+	return fmt.Sprintf("You said: %s", strings.TrimSpace(req.Prompt))
+}
+
+// Generate returns the next scripted reply
+func (f *Fake) Generate(ctx context.Context, req Request) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.next(req), nil
+}
+
+// Stream emits the next scripted reply word by word
+func (f *Fake) Stream(ctx context.Context, req Request, onDelta func(string) error) (string, error) {
+	reply := f.next(req)
+
+	var sent strings.Builder
+	words := strings.SplitAfter(reply, " ")
+	for _, word := range words {
+		if err := ctx.Err(); err != nil {
+			return sent.String(), err
+		}
+		if err := onDelta(word); err != nil {
+			return sent.String(), err
+		}
+		sent.WriteString(word)
+	}
+
+	return reply, nil
+}
+
+// CountTokens estimates the request size
+func (f *Fake) CountTokens(ctx context.Context, req Request) (int, error) {
+	return EstimateRequestTokens(req), nil
+}
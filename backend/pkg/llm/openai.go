@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenAIClient is a Provider for OpenAI-compatible chat completion servers
+// (llama.cpp, Ollama, vLLM, LM Studio, ...)
+type OpenAIClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIClient creates a client for the server at baseURL (e.g. http://localhost:11434/v1)
+func NewOpenAIClient(baseURL, apiKey, model string) *OpenAIClient {
+	return &OpenAIClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// NewOpenAIClientFromEnv creates a client from OPENAI_BASE_URL, OPENAI_API_KEY and OPENAI_MODEL
+func NewOpenAIClientFromEnv() (*OpenAIClient, error) {
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	model := os.Getenv("OPENAI_MODEL")
+	if baseURL == "" || model == "" {
+		return nil, fmt.Errorf("OPENAI_BASE_URL and OPENAI_MODEL environment variables must be set")
+	}
+	return NewOpenAIClient(baseURL, os.Getenv("OPENAI_API_KEY"), model), nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int32         `json:"max_tokens,omitempty"`
+	Temperature *float32      `json:"temperature,omitempty"`
+	TopP        *float32      `json:"top_p,omitempty"`
+	TopK        *int32        `json:"top_k,omitempty"` // Not in the OpenAI API, but llama.cpp, vLLM and Ollama accept it
+	Stop        []string      `json:"stop,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+}
+
+func (o *OpenAIClient) newRequest(req Request, stream bool) chatCompletionRequest {
+	var messages []chatMessage
+	if req.SystemPrompt != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.SystemPrompt})
+	}
+	for _, msg := range req.History {
+		role := "user"
+		if msg.Role == RoleModel {
+			role = "assistant"
+		}
+		messages = append(messages, chatMessage{Role: role, Content: msg.Content})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: req.Prompt})
+
+	// Safety settings have no equivalent here and are ignored
+	return chatCompletionRequest{
+		Model:       o.model,
+		Messages:    messages,
+		MaxTokens:   req.Options.MaxTokens,
+		Temperature: req.Options.Temperature,
+		TopP:        req.Options.TopP,
+		TopK:        req.Options.TopK,
+		Stop:        req.Options.StopSequences,
+		Stream:      stream,
+	}
+}
+
+func (o *OpenAIClient) post(ctx context.Context, body chatCompletionRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &StatusError{StatusCode: http.StatusServiceUnavailable, Message: "failed to reach LLM server: " + err.Error()}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	return resp, nil
+}
+
+// Generate returns the full reply
+func (o *OpenAIClient) Generate(ctx context.Context, req Request) (string, error) {
+	resp, err := o.post(ctx, o.newRequest(req, false))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(out.Choices) == 0 || out.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no response generated")
+	}
+
+	return out.Choices[0].Message.Content, nil
+}
+
+// Stream reads the server's SSE stream ("data: {...}" lines ending with "data: [DONE]")
+func (o *OpenAIClient) Stream(ctx context.Context, req Request, onDelta func(string) error) (string, error) {
+	resp, err := o.post(ctx, o.newRequest(req, true))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		full.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return full.String(), err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	if full.Len() == 0 {
+		return "", fmt.Errorf("no response generated")
+	}
+
+	return full.String(), nil
+}
+
+// CountTokens estimates the request size; the OpenAI API has no tokenizer endpoint
+func (o *OpenAIClient) CountTokens(ctx context.Context, req Request) (int, error) {
+	return EstimateRequestTokens(req), nil
+}
@@ -0,0 +1,56 @@
+package llm
+
+// Harm categories and block thresholds for SafetySetting. Providers without
+// safety filters ignore them.
+const (
+	HarmHarassment       = "harassment"
+	HarmHateSpeech       = "hate_speech"
+	HarmSexuallyExplicit = "sexually_explicit"
+	HarmDangerousContent = "dangerous_content"
+
+	BlockNone           = "block_none"
+	BlockOnlyHigh       = "block_only_high"
+	BlockMediumAndAbove = "block_medium_and_above"
+	BlockLowAndAbove    = "block_low_and_above"
+)
+
+// SafetySetting sets the block threshold for one harm category
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// GenerationOptions are per-request sampling options. Nil/zero fields fall back
+// to the provider's defaults. Options are passed by value and never mutated by
+// providers, so one request can't leak settings into another.
+type GenerationOptions struct {
+	MaxTokens     int32           `json:"max_tokens,omitempty"`
+	Temperature   *float32        `json:"temperature,omitempty"`
+	TopP          *float32        `json:"top_p,omitempty"`
+	TopK          *int32          `json:"top_k,omitempty"`
+	StopSequences []string        `json:"stop_sequences,omitempty"`
+	Safety        []SafetySetting `json:"safety_settings,omitempty"`
+}
+
+// Merge returns a copy of o with every field set in override replaced
+func (o GenerationOptions) Merge(override GenerationOptions) GenerationOptions {
+	if override.MaxTokens > 0 {
+		o.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		o.TopK = override.TopK
+	}
+	if len(override.StopSequences) > 0 {
+		o.StopSequences = append([]string(nil), override.StopSequences...)
+	}
+	if len(override.Safety) > 0 {
+		o.Safety = append([]SafetySetting(nil), override.Safety...)
+	}
+	return o
+}
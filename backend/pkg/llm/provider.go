@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// Role identifies who authored a conversation turn
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleModel Role = "model"
+)
+
+// Message is one turn of an earlier conversation
+type Message struct {
+	Role    Role
+	Content string
+}
+
+// Request is a single companion reply generation request
+type Request struct {
+	SystemPrompt string    // Sent as a system instruction, not as conversation text
+	History      []Message // Earlier turns, oldest first
+	Prompt       string    // The new user message
+	Options      GenerationOptions
+}
+
+// EstimateRequestTokens approximates the input size of a whole request
+func EstimateRequestTokens(req Request) int {
+	total := EstimateTokens(req.SystemPrompt) + EstimateTokens(req.Prompt)
+	for _, msg := range req.History {
+		total += EstimateTokens(msg.Content)
+	}
+	return total
+}
+
+// TrimHistory keeps the most recent turns that fit within budget tokens
+func TrimHistory(history []Message, budget int) []Message {
+	start := len(history)
+	used := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		cost := EstimateTokens(history[i].Content)
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start = i
+	}
+	return history[start:]
+}
+
+// Provider generates companion replies. Implementations must be safe for
+// concurrent use since a single provider is shared by all requests.
+type Provider interface {
+	// Generate returns the full reply
+	Generate(ctx context.Context, req Request) (string, error)
+
+	// Stream calls onDelta with each chunk of the reply as it is generated and
+	// returns the full reply. It stops early when ctx is cancelled or onDelta fails.
+	Stream(ctx context.Context, req Request, onDelta func(string) error) (string, error)
+
+	// CountTokens returns the number of input tokens the request would use
+	CountTokens(ctx context.Context, req Request) (int, error)
+}
+
+// EstimateTokens approximates a token count for providers without a
+// tokenizer endpoint (roughly four characters per token)
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return n/4 + 1
+}
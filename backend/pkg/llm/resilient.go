@@ -0,0 +1,274 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUnavailable is returned when the provider is down: the circuit breaker is
+// open or every retry failed with a transient error
+var ErrUnavailable = errors.New("llm provider unavailable")
+
+// StatusError is a provider error carrying an HTTP-style status code so
+// transient failures (429, 5xx) can be told apart from bad requests
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("LLM server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ResilienceConfig tunes timeouts, retries and circuit breaking
+type ResilienceConfig struct {
+	Timeout           time.Duration // Per attempt; for streams, until the first chunk
+	StreamIdleTimeout time.Duration // Longest a stream may go without a chunk once started
+	MaxRetries        int           // Retries after the first attempt
+	BaseBackoff       time.Duration // Doubled every retry, with full jitter
+	MaxBackoff        time.Duration
+	FailureThreshold  int           // Consecutive transient failures that open the circuit
+	OpenFor           time.Duration // How long the circuit stays open before a trial request
+}
+
+// DefaultResilienceConfig returns the defaults, overridden by LLM_TIMEOUT
+// (a duration like "30s"), LLM_STREAM_IDLE_TIMEOUT and LLM_MAX_RETRIES
+func DefaultResilienceConfig() ResilienceConfig {
+	cfg := ResilienceConfig{
+		Timeout:           30 * time.Second,
+		StreamIdleTimeout: 30 * time.Second,
+		MaxRetries:        2,
+		BaseBackoff:       500 * time.Millisecond,
+		MaxBackoff:        5 * time.Second,
+		FailureThreshold:  5,
+		OpenFor:           30 * time.Second,
+	}
+	if d, err := time.ParseDuration(os.Getenv("LLM_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("LLM_STREAM_IDLE_TIMEOUT")); err == nil && d > 0 {
+		cfg.StreamIdleTimeout = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("LLM_MAX_RETRIES")); err == nil && n >= 0 {
+		cfg.MaxRetries = n
+	}
+	return cfg
+}
+
+// Resilient wraps a Provider with per-attempt timeouts, bounded retries with
+// jittered exponential backoff on transient errors, and a circuit breaker
+type Resilient struct {
+	provider Provider
+	cfg      ResilienceConfig
+	breaker  *breaker
+}
+
+var _ Provider = (*Resilient)(nil)
+
+// NewResilient wraps provider with the given config
+func NewResilient(provider Provider, cfg ResilienceConfig) *Resilient {
+	return &Resilient{
+		provider: provider,
+		cfg:      cfg,
+		breaker:  &breaker{threshold: cfg.FailureThreshold, openFor: cfg.OpenFor},
+	}
+}
+
+// Generate returns the full reply, retrying transient failures
+func (r *Resilient) Generate(ctx context.Context, req Request) (string, error) {
+	var reply string
+	err := r.do(ctx, r.cfg.Timeout, func(ctx context.Context) (bool, error) {
+		var err error
+		reply, err = r.provider.Generate(ctx, req)
+		return true, err
+	})
+	return reply, err
+}
+
+// Stream streams the reply. Rather than a deadline for the whole reply, an
+// attempt times out if the first chunk takes longer than Timeout or the
+// stream then stalls for StreamIdleTimeout, so long replies aren't cut off.
+// A failed attempt is only retried if nothing was sent to onDelta yet, so the
+// caller never sees duplicated text.
+func (r *Resilient) Stream(ctx context.Context, req Request, onDelta func(string) error) (string, error) {
+	var reply string
+	err := r.do(ctx, 0, func(ctx context.Context) (bool, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var stalled atomic.Bool
+		timer := time.AfterFunc(r.cfg.Timeout, func() {
+			stalled.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+
+		sent := false
+		var err error
+		reply, err = r.provider.Stream(ctx, req, func(delta string) error {
+			sent = true
+			timer.Reset(r.cfg.StreamIdleTimeout)
+			return onDelta(delta)
+		})
+		if err != nil && stalled.Load() {
+			err = fmt.Errorf("%w: stream stalled", context.DeadlineExceeded)
+		}
+		return !sent, err
+	})
+	return reply, err
+}
+
+// CountTokens is passed through with a timeout; counting is best effort
+func (r *Resilient) CountTokens(ctx context.Context, req Request) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	return r.provider.CountTokens(ctx, req)
+}
+
+// do runs attempt until it succeeds, fails permanently or runs out of retries.
+// Each attempt gets timeout (none if 0). attempt reports whether it is safe
+// to retry after a failure.
+func (r *Resilient) do(ctx context.Context, timeout time.Duration, attempt func(ctx context.Context) (bool, error)) error {
+	if !r.breaker.allow() {
+		return ErrUnavailable
+	}
+
+	var lastErr error
+	for i := 0; i <= r.cfg.MaxRetries; i++ {
+		if i > 0 {
+			if err := sleep(ctx, r.backoff(i)); err != nil {
+				r.breaker.release()
+				return err
+			}
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		retryable, err := attempt(attemptCtx)
+		cancel()
+
+		if err == nil {
+			r.breaker.success()
+			return nil
+		}
+		// The caller gave up; this says nothing about the provider's health
+		if ctx.Err() != nil {
+			r.breaker.release()
+			return ctx.Err()
+		}
+		if !isTransient(err) {
+			r.breaker.release()
+			return err
+		}
+
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+
+	r.breaker.failure()
+	return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+// backoff returns a random delay in [0, min(MaxBackoff, BaseBackoff*2^(retry-1))]
+func (r *Resilient) backoff(retry int) time.Duration {
+	d := r.cfg.BaseBackoff << (retry - 1)
+	if d <= 0 || d > r.cfg.MaxBackoff {
+		d = r.cfg.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTransient reports whether err is worth retrying: attempt timeouts and
+// errors that say so themselves (like StatusError on 429/5xx)
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var temp interface{ Temporary() bool }
+	if errors.As(err, &temp) {
+		return temp.Temporary()
+	}
+	return false
+}
+
+// breaker is a consecutive-failure circuit breaker. When open, calls fail fast
+// until openFor has passed; then a single trial call is let through (half-open)
+// and its outcome closes or re-opens the circuit.
+type breaker struct {
+	threshold int
+	openFor   time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial call is in flight
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.openFor)
+	}
+}
+
+// release ends a call whose outcome says nothing about provider health
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
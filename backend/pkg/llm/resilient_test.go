@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flaky fails the first calls with the scripted errors, then answers like its Fake
+type flaky struct {
+	*Fake
+
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func newFlaky(errs ...error) *flaky {
+	return &flaky{Fake: NewFake("hello there"), errs: errs}
+}
+
+func (f *flaky) fail() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *flaky) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *flaky) Generate(ctx context.Context, req Request) (string, error) {
+	if err := f.fail(); err != nil {
+		return "", err
+	}
+	return f.Fake.Generate(ctx, req)
+}
+
+func (f *flaky) Stream(ctx context.Context, req Request, onDelta func(string) error) (string, error) {
+	if err := f.fail(); err != nil {
+		return "", err
+	}
+	return f.Fake.Stream(ctx, req, onDelta)
+}
+
+func testResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:           time.Second,
+		StreamIdleTimeout: time.Second,
+		MaxRetries:        2,
+		BaseBackoff:       time.Millisecond,
+		MaxBackoff:        time.Millisecond,
+		FailureThreshold:  2,
+		OpenFor:           time.Hour,
+	}
+}
+
+var (
+	errOverloaded = &StatusError{StatusCode: http.StatusServiceUnavailable, Message: "overloaded"}
+	errBadRequest = &StatusError{StatusCode: http.StatusBadRequest, Message: "bad request"}
+)
+
+func TestResilientRetriesTransientErrors(t *testing.T) {
+	p := newFlaky(errOverloaded, errOverloaded)
+	r := NewResilient(p, testResilienceConfig())
+
+	reply, err := r.Generate(context.Background(), Request{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if reply != "hello there" {
+		t.Errorf("reply = %q, want hello there", reply)
+	}
+	if p.Calls() != 3 {
+		t.Errorf("calls = %d, want 3", p.Calls())
+	}
+}
+
+func TestResilientDoesNotRetryPermanentErrors(t *testing.T) {
+	p := newFlaky(errBadRequest)
+	r := NewResilient(p, testResilienceConfig())
+
+	_, err := r.Generate(context.Background(), Request{Prompt: "hi"})
+	if !errors.Is(err, errBadRequest) {
+		t.Fatalf("err = %v, want the bad request error", err)
+	}
+	if errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, a bad request must not look like an outage", err)
+	}
+	if p.Calls() != 1 {
+		t.Errorf("calls = %d, want 1", p.Calls())
+	}
+}
+
+func TestResilientGivesUpAfterMaxRetries(t *testing.T) {
+	p := newFlaky(errOverloaded, errOverloaded, errOverloaded, errOverloaded)
+	r := NewResilient(p, testResilienceConfig())
+
+	_, err := r.Generate(context.Background(), Request{Prompt: "hi"})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+	if p.Calls() != 3 {
+		t.Errorf("calls = %d, want 3 (1 + MaxRetries)", p.Calls())
+	}
+}
+
+func TestResilientBreakerOpensAndRecovers(t *testing.T) {
+	cfg := testResilienceConfig()
+	cfg.MaxRetries = 0
+	p := newFlaky(errOverloaded, errOverloaded)
+	r := NewResilient(p, cfg)
+
+	for i := 0; i < cfg.FailureThreshold; i++ {
+		if _, err := r.Generate(context.Background(), Request{Prompt: "hi"}); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("call %d: err = %v, want ErrUnavailable", i, err)
+		}
+	}
+
+	// Open: fail fast without calling the provider
+	if _, err := r.Generate(context.Background(), Request{Prompt: "hi"}); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("open circuit: err = %v, want ErrUnavailable", err)
+	}
+	if p.Calls() != cfg.FailureThreshold {
+		t.Fatalf("calls = %d, want %d; an open circuit must not reach the provider", p.Calls(), cfg.FailureThreshold)
+	}
+
+	// Once OpenFor has passed, a successful trial call closes the circuit
+	r.breaker.mu.Lock()
+	r.breaker.openUntil = time.Now().Add(-time.Second)
+	r.breaker.mu.Unlock()
+
+	if _, err := r.Generate(context.Background(), Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	if _, err := r.Generate(context.Background(), Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("closed circuit: %v", err)
+	}
+}
+
+func TestResilientBreakerIgnoresPermanentErrors(t *testing.T) {
+	cfg := testResilienceConfig()
+	p := newFlaky(errBadRequest, errBadRequest, errBadRequest)
+	r := NewResilient(p, cfg)
+
+	for i := 0; i < 3; i++ {
+		_, _ = r.Generate(context.Background(), Request{Prompt: "hi"})
+	}
+	if _, err := r.Generate(context.Background(), Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("err = %v, bad requests must not open the circuit", err)
+	}
+}
+
+// midStreamFailure sends one chunk, then fails transiently
+type midStreamFailure struct {
+	*flaky
+}
+
+func (m midStreamFailure) Stream(ctx context.Context, req Request, onDelta func(string) error) (string, error) {
+	m.fail()
+	if err := onDelta("hel"); err != nil {
+		return "", err
+	}
+	return "", errOverloaded
+}
+
+func TestResilientStreamNotRetriedAfterFirstChunk(t *testing.T) {
+	p := midStreamFailure{newFlaky()}
+	r := NewResilient(p, testResilienceConfig())
+
+	var deltas []string
+	_, err := r.Stream(context.Background(), Request{Prompt: "hi"}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+	if p.Calls() != 1 || len(deltas) != 1 {
+		t.Errorf("calls = %d, deltas = %q; a started stream must not be retried", p.Calls(), deltas)
+	}
+}
+
+func TestResilientStreamRetriedBeforeFirstChunk(t *testing.T) {
+	p := newFlaky(errOverloaded)
+	r := NewResilient(p, testResilienceConfig())
+
+	var got string
+	reply, err := r.Stream(context.Background(), Request{Prompt: "hi"}, func(delta string) error {
+		got += delta
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if reply != "hello there" || got != reply {
+		t.Errorf("reply = %q, streamed = %q", reply, got)
+	}
+	if p.Calls() != 2 {
+		t.Errorf("calls = %d, want 2", p.Calls())
+	}
+}
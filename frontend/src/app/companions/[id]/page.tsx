@@ -120,9 +120,9 @@ export default function CompanionDetailPage() {
             </div>
 
             {/* Affinity Display */}
-            {companion.affinity && (
+            {companion.relationship && (
               <AffinityDisplay
-                affinity={companion.affinity}
+                relationship={companion.relationship}
                 companionName={companion.name}
               />
             )}
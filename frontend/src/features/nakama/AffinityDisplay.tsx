@@ -1,19 +1,19 @@
 "use client";
 
 import { useEffect, useState } from "react";
-import { UserAffinity } from "@/types";
+import { Relationship } from "@/types";
 
 interface AffinityDisplayProps {
-  affinity: UserAffinity;
+  relationship: Relationship;
   companionName: string;
 }
 
 export default function AffinityDisplay({
-  affinity,
+  relationship,
   companionName,
 }: AffinityDisplayProps) {
   const [showLevelUp, setShowLevelUp] = useState(false);
-  const xpInCurrentLevel = affinity.xp % 100;
+  const xpInCurrentLevel = relationship.xp % 100;
   const xpForNextLevel = 100;
   const progress = (xpInCurrentLevel / xpForNextLevel) * 100;
 
@@ -23,7 +23,7 @@ export default function AffinityDisplay({
         <h3 className="text-xl font-bold text-white">Nakama Level</h3>
         <div className="px-4 py-2 bg-gradient-to-r from-anikama-orange to-anikama-orange-light rounded-full">
           <span className="text-2xl font-bold text-black">
-            {affinity.level}
+            {relationship.level}
           </span>
         </div>
       </div>
@@ -46,7 +46,7 @@ export default function AffinityDisplay({
         </div>
 
         <p className="text-xs text-gray-500 text-right">
-          {100 - xpInCurrentLevel} XP to level {affinity.level + 1}
+          {100 - xpInCurrentLevel} XP to level {relationship.level + 1}
         </p>
       </div>
 
@@ -54,12 +54,12 @@ export default function AffinityDisplay({
       <div className="mt-6 grid grid-cols-2 gap-4">
         <div className="bg-gray-800 rounded-lg p-3">
           <p className="text-xs text-gray-400 mb-1">Total XP</p>
-          <p className="text-lg font-bold text-anikama-orange">{affinity.xp}</p>
+          <p className="text-lg font-bold text-anikama-orange">{relationship.xp}</p>
         </div>
         <div className="bg-gray-800 rounded-lg p-3">
           <p className="text-xs text-gray-400 mb-1">Last Chat</p>
           <p className="text-sm font-semibold text-white">
-            {new Date(affinity.last_interaction).toLocaleDateString()}
+            {new Date(relationship.last_interaction_at).toLocaleDateString()}
           </p>
         </div>
       </div>
@@ -70,6 +70,8 @@ export default function AffinityDisplay({
           💬 <strong>+1 XP</strong> per message sent
           <br />
           📖 <strong>+5 XP</strong> per story viewed
+          <br />
+          🎁 <strong>+10 XP</strong> per gift sent
         </p>
       </div>
     </div>
@@ -1,14 +1,24 @@
 import { useQuery } from '@tanstack/react-query';
-import { Companion, CompanionWithAffinity } from '@/types';
+import { supabase } from '@/lib/supabase';
+import { Companion } from '@/types';
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
+// Send the session token, if any, so responses include the user's relationships
+const authHeaders = async (): Promise<HeadersInit> => {
+  const { data: { session } } = await supabase.auth.getSession();
+  const token = session?.access_token;
+  return token ? { 'Authorization': `Bearer ${token}` } : {};
+};
+
 // Fetch all companions
 export const useCompanions = () => {
   return useQuery({
     queryKey: ['companions'],
     queryFn: async (): Promise<Companion[]> => {
-      const response = await fetch(`${API_URL}/api/v1/companions`);
+      const response = await fetch(`${API_URL}/api/v1/companions`, {
+        headers: await authHeaders(),
+      });
       if (!response.ok) {
         throw new Error('Failed to fetch companions');
       }
@@ -18,12 +28,14 @@ export const useCompanions = () => {
   });
 };
 
-// Fetch single companion by ID
+// Fetch single companion by ID, with the user's relationship when logged in
 export const useCompanion = (id: string) => {
   return useQuery({
     queryKey: ['companion', id],
-    queryFn: async (): Promise<CompanionWithAffinity> => {
-      const response = await fetch(`${API_URL}/api/v1/companions/${id}`);
+    queryFn: async (): Promise<Companion> => {
+      const response = await fetch(`${API_URL}/api/v1/companions/${id}`, {
+        headers: await authHeaders(),
+      });
       if (!response.ok) {
         throw new Error('Failed to fetch companion');
       }
@@ -7,6 +7,8 @@ export interface RelationshipData {
   found: boolean;
   affinity_score: number;
   current_mood: string;
+  xp?: number;
+  level?: number;
   last_interaction_at?: string;
 }
 
@@ -46,6 +48,10 @@ export interface InteractResponse {
   new_mood: string;
   toast_message: string;
   reaction_video_url?: string;
+  xp: number;
+  level: number;
+  xp_gained: number;
+  leveled_up: boolean;
 }
 
 export const useInteract = () => {
@@ -111,6 +117,7 @@ export const useInteract = () => {
         // If successful, update with actual data from server response if needed, 
         // but invalidating is safer to get the exact state including mood changes
         queryClient.invalidateQueries({ queryKey: ['relationship', variables.companionId] });
+        queryClient.invalidateQueries({ queryKey: ['companion', variables.companionId] });
     },
   });
 };
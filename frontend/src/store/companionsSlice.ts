@@ -1,9 +1,9 @@
 import { createSlice, PayloadAction } from '@reduxjs/toolkit';
-import { Companion, CompanionWithAffinity, StoriesGrouped } from '@/types';
+import { Companion, StoriesGrouped } from '@/types';
 
 interface CompanionsState {
   companions: Companion[];
-  selectedCompanion: CompanionWithAffinity | null;
+  selectedCompanion: Companion | null;
   stories: StoriesGrouped[];
   isLoading: boolean;
   error: string | null;
@@ -26,7 +26,7 @@ export const companionsSlice = createSlice({
       state.isLoading = false;
       state.error = null;
     },
-    setSelectedCompanion: (state, action: PayloadAction<CompanionWithAffinity | null>) => {
+    setSelectedCompanion: (state, action: PayloadAction<Companion | null>) => {
       state.selectedCompanion = action.payload;
     },
     setStories: (state, action: PayloadAction<StoriesGrouped[]>) => {
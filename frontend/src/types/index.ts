@@ -28,6 +28,8 @@ export interface Relationship {
   companion_id: string;
   affinity_score: number;
   current_mood: string;
+  xp: number;
+  level: number;
   last_interaction_at: string;
 }
 
@@ -45,19 +47,6 @@ export interface Story {
   created_at: string;
 }
 
-export interface UserAffinity {
-  user_id: string;
-  companion_id: string;
-  xp: number;
-  level: number;
-  last_interaction: string;
-  created_at: string;
-}
-
-export interface CompanionWithAffinity extends Companion {
-  affinity?: UserAffinity;
-}
-
 export interface StoriesGrouped {
   companion_id: string;
   companion_name: string;
@@ -76,24 +65,6 @@ export interface ChatResponse {
   is_limited: boolean;
 }
 
-export interface InteractionRequest {
-  companion_id: string;
-  action: 'view_story' | 'sent_msg';
-}
-
-export interface InteractionResponse {
-  companion_id: string;
-  xp: number;
-  level: number;
-  xp_gained: number;
-  leveled_up: boolean;
-  new_score: number;
-  delta: number;
-  new_mood: string;
-  toast_message: string;
-  reaction_video_url?: string;
-}
-
 export interface RegisterRequest {
   username: string;
   password: string;
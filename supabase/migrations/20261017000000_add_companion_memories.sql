@@ -0,0 +1,24 @@
+-- Long-term companion memory: a rolling summary and extracted facts per (user, companion)
+CREATE TABLE IF NOT EXISTS companion_memories (
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    companion_id UUID NOT NULL REFERENCES companions(id) ON DELETE CASCADE,
+    summary TEXT NOT NULL DEFAULT '',
+    facts TEXT[] NOT NULL DEFAULT '{}',
+    -- created_at of the newest message already folded into the summary
+    summarized_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, companion_id)
+);
+
+-- RLS Policies
+ALTER TABLE companion_memories ENABLE ROW LEVEL SECURITY;
+
+-- Users can read what companions remember about them
+CREATE POLICY "Users can read their own companion memories"
+    ON companion_memories FOR SELECT
+    USING (auth.uid() = user_id);
+
+-- Users can wipe what companions remember about them
+CREATE POLICY "Users can delete their own companion memories"
+    ON companion_memories FOR DELETE
+    USING (auth.uid() = user_id);
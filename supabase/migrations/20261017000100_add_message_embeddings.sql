@@ -0,0 +1,29 @@
+-- Semantic recall: one embedding per finished exchange (user message + companion reply)
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS message_embeddings (
+    -- The assistant message that closed the exchange
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    companion_id UUID NOT NULL REFERENCES companions(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    -- Must match the embedder's dimensions (768 for text-embedding-004 and the local embedder)
+    embedding vector(768) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Recall is an exact scan of one (user, companion)'s rows, found through this
+-- index. There is deliberately no HNSW/IVFFlat index on embedding: an
+-- approximate index over every user's rows would return the nearest
+-- neighbours across all users first and filter afterwards, leaving most
+-- searches with no rows once the table holds more than a few users.
+CREATE INDEX IF NOT EXISTS idx_message_embeddings_user_companion
+    ON message_embeddings(user_id, companion_id, created_at);
+
+-- RLS Policies
+ALTER TABLE message_embeddings ENABLE ROW LEVEL SECURITY;
+
+-- Users can wipe what companions recall about them
+CREATE POLICY "Users can delete their own message embeddings"
+    ON message_embeddings FOR DELETE
+    USING (auth.uid() = user_id);
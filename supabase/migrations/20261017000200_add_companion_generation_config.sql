@@ -0,0 +1,6 @@
+-- Per-companion generation options with optional per-tier overrides, e.g.
+-- {"default": {"temperature": 0.8, "stop_sequences": ["User:"]},
+--  "tiers": {"free": {"max_tokens": 512}, "premium": {"max_tokens": 1024}}}
+-- Options: max_tokens, temperature, top_p, top_k, stop_sequences,
+-- safety_settings ([{"category": "harassment", "threshold": "block_only_high"}])
+ALTER TABLE companions ADD COLUMN IF NOT EXISTS generation_config JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,35 @@
+-- Daily free chat messages per tier and the Hush Coin cost of each message after that.
+-- daily_messages = -1 means unlimited. Tiers without a row are not metered.
+CREATE TABLE IF NOT EXISTS chat_quotas (
+    tier TEXT PRIMARY KEY,
+    daily_messages INTEGER NOT NULL CHECK (daily_messages >= -1),
+    message_cost INTEGER NOT NULL CHECK (message_cost >= 0)
+);
+
+INSERT INTO chat_quotas (tier, daily_messages, message_cost) VALUES
+    ('free', 30, 10),
+    ('premium', 300, 2)
+ON CONFLICT (tier) DO NOTHING;
+
+-- Messages sent per user per UTC day
+CREATE TABLE IF NOT EXISTS chat_usage (
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    messages INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+-- RLS Policies
+ALTER TABLE chat_quotas ENABLE ROW LEVEL SECURITY;
+ALTER TABLE chat_usage ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Anyone can read chat quotas"
+    ON chat_quotas FOR SELECT
+    USING (true);
+
+CREATE POLICY "Users can read their own chat usage"
+    ON chat_usage FOR SELECT
+    USING (auth.uid() = user_id);
+
+-- Balances must never go negative now that chat debits them
+ALTER TABLE profiles ADD CONSTRAINT profiles_hush_coins_non_negative CHECK (hush_coins >= 0);
@@ -0,0 +1,70 @@
+-- Append-only double-entry Hush Coin ledger.
+-- Every balance change is a ledger transaction whose entries sum to zero.
+-- Accounts are 'user:<profile id>' or system accounts like 'system:deposits'.
+-- profiles.hush_coins is a cached balance kept in sync in the same database
+-- transaction and can be recomputed from ledger_entries at any time.
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL,            -- 'deposit', 'chat_message', 'reversal', ...
+    reference TEXT NOT NULL DEFAULT '', -- e.g. tx hash, message id
+    reverses UUID UNIQUE REFERENCES ledger_transactions(id), -- set on reversals; at most one per transaction
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    seq BIGSERIAL PRIMARY KEY,     -- Global order, used as the pagination cursor
+    transaction_id UUID NOT NULL REFERENCES ledger_transactions(id),
+    account TEXT NOT NULL,
+    user_id UUID, -- Set for user accounts; no FK so history outlives deleted profiles
+    amount BIGINT NOT NULL CHECK (amount <> 0), -- Positive credits the account, negative debits it
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account, seq);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user ON ledger_entries(user_id, seq);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction ON ledger_entries(transaction_id);
+
+-- The ledger is append-only: corrections are new (reversal) transactions
+CREATE OR REPLACE FUNCTION ledger_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+CREATE TRIGGER ledger_entries_append_only
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+DROP TRIGGER IF EXISTS ledger_transactions_append_only ON ledger_transactions;
+CREATE TRIGGER ledger_transactions_append_only
+    BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+-- Balance per account, derived from the ledger
+CREATE OR REPLACE VIEW ledger_balances AS
+    SELECT account, user_id, SUM(amount) AS balance
+    FROM ledger_entries
+    GROUP BY account, user_id;
+
+-- Opening balances: carry existing profile balances into the ledger
+DO $$
+DECLARE
+    p RECORD;
+    tx_id UUID;
+BEGIN
+    FOR p IN SELECT id, hush_coins FROM profiles WHERE COALESCE(hush_coins, 0) > 0 LOOP
+        INSERT INTO ledger_transactions (kind, reference) VALUES ('opening_balance', '') RETURNING id INTO tx_id;
+        INSERT INTO ledger_entries (transaction_id, account, user_id, amount) VALUES
+            (tx_id, 'system:opening_balance', NULL, -p.hush_coins),
+            (tx_id, 'user:' || p.id, p.id, p.hush_coins);
+    END LOOP;
+END $$;
+
+-- RLS Policies
+ALTER TABLE ledger_transactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE ledger_entries ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view their own ledger entries"
+    ON ledger_entries FOR SELECT
+    USING (auth.uid() = user_id);
@@ -0,0 +1,18 @@
+-- Deposits are recorded as 'pending' when submitted and confirmed (or failed) by the
+-- deposit worker once the transaction has enough confirmations on chain
+ALTER TABLE transactions
+ADD COLUMN IF NOT EXISTS from_address TEXT,               -- Expected sender (user's wallet), if known
+ADD COLUMN IF NOT EXISTS amount_wei NUMERIC(78, 0),       -- Claimed value in wei
+ADD COLUMN IF NOT EXISTS confirmations INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS failure_reason TEXT,
+ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE transactions SET status = 'confirmed' WHERE status IS NULL;
+
+ALTER TABLE transactions
+ALTER COLUMN status SET NOT NULL,
+ADD CONSTRAINT transactions_status_check CHECK (status IN ('pending', 'confirmed', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_transactions_pending ON transactions(checked_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_transactions_pending_user ON transactions(user_id) WHERE status = 'pending';
@@ -0,0 +1,37 @@
+-- One outstanding wallet-link challenge per user; consumed when the wallet is linked
+CREATE TABLE IF NOT EXISTS wallet_challenges (
+    user_id UUID PRIMARY KEY REFERENCES profiles(id) ON DELETE CASCADE,
+    nonce TEXT NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+ALTER TABLE wallet_challenges ENABLE ROW LEVEL SECURITY;
+
+-- A wallet can only be linked to one account
+UPDATE profiles SET wallet_address = LOWER(wallet_address) WHERE wallet_address IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_profiles_wallet_address ON profiles(wallet_address) WHERE wallet_address IS NOT NULL;
+
+-- The baseline "Users can update their own profile" policy lets clients PATCH
+-- any column through the REST API. Wallets must go through the signed
+-- challenge and coins/tier through the ledger, so only the backend (or the
+-- service role) may change them. A trigger is used because column-level
+-- REVOKE doesn't override the table-level UPDATE grant.
+CREATE OR REPLACE FUNCTION protect_profile_privileged_columns()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_user IN ('anon', 'authenticated')
+       AND (NEW.wallet_address IS DISTINCT FROM OLD.wallet_address
+            OR NEW.hush_coins IS DISTINCT FROM OLD.hush_coins
+            OR NEW.tier IS DISTINCT FROM OLD.tier) THEN
+        RAISE EXCEPTION 'wallet_address, hush_coins and tier can only be changed by the server'
+            USING ERRCODE = 'insufficient_privilege';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS protect_profile_privileged_columns ON profiles;
+CREATE TRIGGER protect_profile_privileged_columns
+    BEFORE UPDATE ON profiles
+    FOR EACH ROW EXECUTE FUNCTION protect_profile_privileged_columns();
@@ -0,0 +1,56 @@
+-- ETH to Hush Coin exchange rates. The rate in effect at a given time is the
+-- one with the latest effective_from not after it, so new rates can be scheduled.
+CREATE TABLE IF NOT EXISTS coin_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    coins_per_eth BIGINT NOT NULL CHECK (coins_per_eth > 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_coin_rates_effective ON coin_rates(effective_from DESC);
+
+-- The original hard-coded rate: 0.001 ETH = 100 Hush Coins
+INSERT INTO coin_rates (coins_per_eth, effective_from)
+SELECT 100000, '2024-01-01T00:00:00Z'
+WHERE NOT EXISTS (SELECT 1 FROM coin_rates);
+
+-- Coin packs are priced from the current rate; larger packs add bonus coins
+CREATE TABLE IF NOT EXISTS coin_packs (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    coins INTEGER NOT NULL CHECK (coins > 0),        -- Priced coins
+    bonus_coins INTEGER NOT NULL DEFAULT 0 CHECK (bonus_coins >= 0), -- Free extra coins
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO coin_packs (id, name, coins, bonus_coins, sort_order) VALUES
+    ('starter', 'Starter Pouch', 100, 0, 1),
+    ('popular', 'Popular Chest', 500, 50, 2),
+    ('hero', 'Hero Vault', 1000, 150, 3),
+    ('legend', 'Legend Hoard', 5000, 1000, 4)
+ON CONFLICT (id) DO NOTHING;
+
+-- Which pack and rate a deposit was priced with
+ALTER TABLE transactions
+ADD COLUMN IF NOT EXISTS pack_id TEXT REFERENCES coin_packs(id),
+ADD COLUMN IF NOT EXISTS rate_id UUID REFERENCES coin_rates(id);
+
+-- RLS Policies
+ALTER TABLE coin_rates ENABLE ROW LEVEL SECURITY;
+ALTER TABLE coin_packs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Anyone can read coin rates"
+    ON coin_rates FOR SELECT
+    USING (true);
+
+CREATE POLICY "Anyone can read active coin packs"
+    ON coin_packs FOR SELECT
+    USING (active);
+
+-- Payments that couldn't be priced automatically (e.g. the pack price changed
+-- after the ETH was sent) are kept for review instead of being dropped
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'confirmed', 'failed', 'needs_review'));
@@ -0,0 +1,22 @@
+-- Premium stories can be bought one at a time with Hush Coins
+ALTER TABLE stories
+ADD COLUMN IF NOT EXISTS coin_price INTEGER NOT NULL DEFAULT 50 CHECK (coin_price > 0);
+
+-- Stories a user has bought; the entitlement outlives tier changes
+CREATE TABLE IF NOT EXISTS story_unlocks (
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    story_id UUID NOT NULL REFERENCES stories(id) ON DELETE CASCADE,
+    coins_spent INTEGER NOT NULL,
+    ledger_transaction_id UUID NOT NULL REFERENCES ledger_transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, story_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_story_unlocks_story ON story_unlocks(story_id);
+
+-- RLS Policies
+ALTER TABLE story_unlocks ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view their own story unlocks"
+    ON story_unlocks FOR SELECT
+    USING (auth.uid() = user_id);
@@ -0,0 +1,40 @@
+-- Premium plans bought with Hush Coins for a number of days
+CREATE TABLE IF NOT EXISTS subscription_plans (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    days INTEGER NOT NULL CHECK (days > 0),
+    coin_price INTEGER NOT NULL CHECK (coin_price > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO subscription_plans (id, name, days, coin_price, sort_order) VALUES
+    ('premium_week', 'Premium Week', 7, 300, 1),
+    ('premium_month', 'Premium Month', 30, 1000, 2)
+ON CONFLICT (id) DO NOTHING;
+
+-- Each user's current subscription. 'active' lasts until expires_at plus the grace
+-- period, after which it is marked 'expired' and the profile is downgraded to free.
+-- Premium profiles without a subscription row (set by hand) are never downgraded.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES profiles(id) ON DELETE CASCADE,
+    plan_id TEXT NOT NULL REFERENCES subscription_plans(id),
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'expired')),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_active_expiry ON subscriptions(expires_at) WHERE status = 'active';
+
+-- RLS Policies
+ALTER TABLE subscription_plans ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Anyone can read active subscription plans"
+    ON subscription_plans FOR SELECT
+    USING (active);
+
+CREATE POLICY "Users can view their own subscription"
+    ON subscriptions FOR SELECT
+    USING (auth.uid() = user_id);
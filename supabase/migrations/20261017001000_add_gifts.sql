@@ -0,0 +1,66 @@
+-- Gifts users can buy for companions with Hush Coins
+CREATE TABLE IF NOT EXISTS gifts (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    emoji TEXT NOT NULL DEFAULT '🎁',
+    coin_price INTEGER NOT NULL CHECK (coin_price > 0),
+    base_delta INTEGER NOT NULL, -- Affinity change for personalities without a preference
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO gifts (id, name, emoji, coin_price, base_delta, sort_order) VALUES
+    ('flowers', 'Bouquet of Flowers', '💐', 50, 5, 1),
+    ('chocolate', 'Box of Chocolates', '🍫', 80, 8, 2),
+    ('ramen', 'Bowl of Ramen', '🍜', 100, 8, 3),
+    ('plushie', 'Plushie', '🧸', 150, 12, 4),
+    ('love_letter', 'Love Letter', '💌', 200, 15, 5),
+    ('rare_figure', 'Rare Figure', '🗿', 500, 25, 6)
+ON CONFLICT (id) DO NOTHING;
+
+-- How each personality takes a gift, overriding its base delta and toast
+CREATE TABLE IF NOT EXISTS gift_preferences (
+    gift_id TEXT NOT NULL REFERENCES gifts(id) ON DELETE CASCADE,
+    personality_type TEXT NOT NULL,
+    delta INTEGER NOT NULL,
+    toast_message TEXT,
+    PRIMARY KEY (gift_id, personality_type)
+);
+
+INSERT INTO gift_preferences (gift_id, personality_type, delta, toast_message) VALUES
+    ('flowers', 'Tsundere', 6, '"I-it''s not like I wanted flowers, baka!" (She''s keeping them.)'),
+    ('love_letter', 'Tsundere', 10, '"Wh-what is this?! ...I''ll read it later. Alone."'),
+    ('flowers', 'Kuudere', 2, '"...Thank you." They seem indifferent, but noted it.'),
+    ('rare_figure', 'Kuudere', 30, '"...Where did you find this?" Their eyes lit up.'),
+    ('love_letter', 'Deredere', 25, 'They''re hugging the letter and won''t stop smiling!'),
+    ('plushie', 'Deredere', 18, 'They named the plushie after you!'),
+    ('chocolate', 'Ore-sama', -2, '"Cheap chocolate? For me? Try harder."'),
+    ('rare_figure', 'Ore-sama', 35, '"Finally, a gift worthy of me."')
+ON CONFLICT (gift_id, personality_type) DO NOTHING;
+
+-- Gifts sent, for history
+CREATE TABLE IF NOT EXISTS gifts_sent (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    companion_id UUID NOT NULL REFERENCES companions(id) ON DELETE CASCADE,
+    gift_id TEXT NOT NULL REFERENCES gifts(id),
+    coins_spent INTEGER NOT NULL,
+    delta INTEGER NOT NULL,
+    ledger_transaction_id UUID NOT NULL REFERENCES ledger_transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gifts_sent_user_companion ON gifts_sent(user_id, companion_id, created_at DESC);
+
+-- RLS Policies
+ALTER TABLE gifts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE gift_preferences ENABLE ROW LEVEL SECURITY;
+ALTER TABLE gifts_sent ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Anyone can read active gifts"
+    ON gifts FOR SELECT
+    USING (active);
+
+CREATE POLICY "Users can view their own sent gifts"
+    ON gifts_sent FOR SELECT
+    USING (auth.uid() = user_id);
@@ -0,0 +1,5 @@
+-- CalculateMood has always returned 'sad' below -20, but the original
+-- constraint rejected it, so saving any relationship that low failed
+ALTER TABLE relationships DROP CONSTRAINT IF EXISTS relationships_current_mood_check;
+ALTER TABLE relationships ADD CONSTRAINT relationships_current_mood_check
+    CHECK (current_mood IN ('neutral', 'happy', 'jealous', 'annoyed', 'flirty', 'sad'));
@@ -0,0 +1,23 @@
+-- Responses to mutating requests sent with an Idempotency-Key header, replayed on
+-- retries until expires_at. status_code is NULL while the first request runs,
+-- which renews locked_at as it goes; if locked_at is more than a minute old
+-- (the server died mid-request), a retry may take the key over. Only the
+-- request holding lease_token may store a response or release the key.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL, -- SHA-256 of method, path, query and body
+    status_code INTEGER,
+    content_type TEXT,
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    lease_token TEXT NOT NULL,
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+
+-- Only the backend reads and writes these
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
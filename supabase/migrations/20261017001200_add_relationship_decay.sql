@@ -0,0 +1,29 @@
+-- Relationships cool off while the user is away. decayed_at is how far decay has
+-- been applied, so each pass only takes off the days since the last one.
+ALTER TABLE relationships
+ADD COLUMN IF NOT EXISTS decayed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_relationships_last_interaction ON relationships(last_interaction_at);
+
+-- Changes made to relationships outside of user actions (e.g. decay)
+CREATE TABLE IF NOT EXISTS relationship_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    companion_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    old_score INTEGER NOT NULL,
+    new_score INTEGER NOT NULL,
+    old_mood TEXT NOT NULL,
+    new_mood TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (user_id, companion_id) REFERENCES relationships(user_id, companion_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_relationship_events_relationship ON relationship_events(user_id, companion_id, created_at DESC);
+
+-- RLS Policies
+ALTER TABLE relationship_events ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view their own relationship events"
+    ON relationship_events FOR SELECT
+    USING (auth.uid() = user_id);
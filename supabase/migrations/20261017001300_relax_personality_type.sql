@@ -0,0 +1,3 @@
+-- Personalities are defined by the personality rules file now, so new ones
+-- (e.g. Yandere, Dandere) don't need a schema change
+ALTER TABLE companions DROP CONSTRAINT IF EXISTS companions_personality_type_check;
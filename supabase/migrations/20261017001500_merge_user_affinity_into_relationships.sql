@@ -0,0 +1,33 @@
+-- One progression model per user and companion: relationships keeps the
+-- bounded affinity score/mood and now also the unbounded Nakama XP/level
+-- that used to live in user_affinity. level = floor(xp / 100) + 1.
+ALTER TABLE relationships
+ADD COLUMN IF NOT EXISTS xp INTEGER NOT NULL DEFAULT 0 CHECK (xp >= 0),
+ADD COLUMN IF NOT EXISTS level INTEGER NOT NULL DEFAULT 1 CHECK (level >= 1),
+ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+-- Merge user_affinity rows. Users with both keep their score and mood, gain
+-- the XP, and keep whichever interaction is the most recent.
+DO $$
+BEGIN
+    IF to_regclass('public.user_affinity') IS NULL THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO relationships (user_id, companion_id, xp, level, last_interaction_at, created_at)
+    SELECT user_id,
+           companion_id,
+           GREATEST(COALESCE(xp, 0), 0),
+           GREATEST(COALESCE(xp, 0), 0) / 100 + 1,
+           COALESCE(last_interaction, NOW()),
+           COALESCE(created_at, NOW())
+    FROM user_affinity
+    ON CONFLICT (user_id, companion_id) DO UPDATE SET
+        xp = relationships.xp + EXCLUDED.xp,
+        level = (relationships.xp + EXCLUDED.xp) / 100 + 1,
+        last_interaction_at = GREATEST(relationships.last_interaction_at, EXCLUDED.last_interaction_at),
+        created_at = LEAST(relationships.created_at, EXCLUDED.created_at);
+
+    -- Kept for reference until the merge has been checked; nothing reads it
+    ALTER TABLE user_affinity RENAME TO user_affinity_legacy;
+END $$;
@@ -0,0 +1,14 @@
+-- Stories each user has viewed, so viewing XP is only awarded once per story
+CREATE TABLE IF NOT EXISTS story_views (
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    story_id UUID NOT NULL REFERENCES stories(id) ON DELETE CASCADE,
+    viewed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, story_id)
+);
+
+-- Only the backend writes these
+ALTER TABLE story_views ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users can view their own story views"
+    ON story_views FOR SELECT
+    USING (auth.uid() = user_id);